@@ -16,7 +16,6 @@ package fn
 
 import (
 	"context"
-	"encoding/json"
 	"net/http"
 	"reflect"
 )
@@ -24,7 +23,10 @@ import (
 // adapter represents a container that contain a handler function
 // and convert a it to a http.Handler
 type adapter interface {
-	invoke(context.Context, http.ResponseWriter, *http.Request) (interface{}, error)
+	// args extracts the arguments of handler from the request
+	args(context.Context, *http.Request, DecodeOption) ([]reflect.Value, error)
+	// call calls the handler with the arguments
+	call([]reflect.Value) (interface{}, int, error)
 }
 
 // genericAdapter represents a common adapter
@@ -33,14 +35,25 @@ type genericAdapter struct {
 	method    reflect.Value
 	numIn     int
 	types     []reflect.Type
-	cacheArgs []reflect.Value // cache args
+	sources   []argSource
 }
 
+// argSource represents where an argument of genericAdapter comes from
+type argSource int
+
+const (
+	sourceBuiltin      argSource = iota // supportTypes
+	sourceContext                       // context.Context
+	sourceContextValue                  // contextTypes
+	sourceForm                          // bound from request.Form
+	sourceBinder                        // bound by the parameter type itself, e.g. Query[T]
+	sourceBody                          // decoded from JSON body
+)
+
 // Accept zero parameter adapter
 type simplePlainAdapter struct {
 	inContext bool
 	method    reflect.Value
-	cacheArgs []reflect.Value
 }
 
 // Accept only one parameter adapter
@@ -48,11 +61,10 @@ type simpleUnaryAdapter struct {
 	outContext bool
 	argType    reflect.Type
 	method     reflect.Value
-	cacheArgs  []reflect.Value // cache args
 }
 
 func makeGenericAdapter(method reflect.Value, inContext bool) *genericAdapter {
-	var noSupportExists = false
+	var bodyConsumed = false
 	t := method.Type()
 	numIn := t.NumIn()
 
@@ -61,20 +73,32 @@ func makeGenericAdapter(method reflect.Value, inContext bool) *genericAdapter {
 		method:    method,
 		numIn:     numIn,
 		types:     make([]reflect.Type, numIn),
-		cacheArgs: make([]reflect.Value, numIn),
+		sources:   make([]argSource, numIn),
 	}
 
 	for i := 0; i < numIn; i++ {
 		in := t.In(i)
-		if in != contextType && !isBuiltinType(in) {
-			if noSupportExists {
+		switch {
+		case isBuiltinType(in):
+			a.sources[i] = sourceBuiltin
+		case in == contextType:
+			a.sources[i] = sourceContext
+		case isContextValueType(in):
+			a.sources[i] = sourceContextValue
+		case isFormBinding(in):
+			a.sources[i] = sourceForm
+		case isBinder(in):
+			checkBinder(in)
+			a.sources[i] = sourceBinder
+		default:
+			checkCustomizedType(in)
+			a.sources[i] = sourceBody
+		}
+		if consumesBody(in) {
+			if bodyConsumed {
 				panic("function should accept only one customize type")
 			}
-
-			if in.Kind() != reflect.Ptr {
-				panic("customize type should be a pointer(" + in.PkgPath() + "." + in.Name() + ")")
-			}
-			noSupportExists = true
+			bodyConsumed = true
 		}
 		a.types[i] = in
 	}
@@ -82,64 +106,136 @@ func makeGenericAdapter(method reflect.Value, inContext bool) *genericAdapter {
 	return a
 }
 
-func (a *genericAdapter) invoke(ctx context.Context, w http.ResponseWriter, r *http.Request) (interface{}, error) {
-	values := a.cacheArgs
+func (a *genericAdapter) args(ctx context.Context, r *http.Request, opts DecodeOption) ([]reflect.Value, error) {
+	values := make([]reflect.Value, a.numIn)
 	for i := 0; i < a.numIn; i++ {
 		typ := a.types[i]
-		v, ok := supportTypes[typ]
-		if ok {
-			value, err := v(r)
+		switch a.sources[i] {
+		case sourceBuiltin:
+			value, err := supportTypes[typ](r)
 			if err != nil {
 				return nil, err
 			}
 			values[i] = value
-		} else if typ == contextType {
+		case sourceContext:
 			values[i] = reflect.ValueOf(ctx)
-		} else {
-			d := reflect.New(a.types[i].Elem()).Interface()
-			err := json.NewDecoder(r.Body).Decode(d)
+		case sourceContextValue:
+			value, err := contextTypes[typ](ctx)
+			if err != nil {
+				return nil, err
+			}
+			values[i] = value
+		case sourceForm:
+			if err := r.ParseForm(); err != nil {
+				return nil, formError(err)
+			}
+			d := reflect.New(indirectType(typ))
+			if err := bind(urlValuesSource(r.Form), "form", d.Interface()); err != nil {
+				return nil, err
+			}
+			values[i] = passAs(d, typ)
+		case sourceBinder:
+			d := reflect.New(typ)
+			if err := d.Interface().(binder).bindRequest(r, opts); err != nil {
+				return nil, err
+			}
+			values[i] = d.Elem()
+		default:
+			d, err := decodeRequest(r, typ, opts)
 			if err != nil {
 				return nil, err
 			}
-			values[i] = reflect.ValueOf(d)
+			values[i] = d
 		}
 	}
 
-	var err error
-	results := a.method.Call(values)
-	payload := results[0].Interface()
-	if e := results[1].Interface(); e != nil {
-		err = e.(error)
-	}
-	return payload, err
+	return values, nil
 }
 
-func (a *simplePlainAdapter) invoke(ctx context.Context, w http.ResponseWriter, r *http.Request) (interface{}, error) {
+func (a *genericAdapter) call(args []reflect.Value) (interface{}, int, error) {
+	return results(a.method.Call(args))
+}
+
+func (a *simplePlainAdapter) args(ctx context.Context, r *http.Request, opts DecodeOption) ([]reflect.Value, error) {
 	if a.inContext {
-		a.cacheArgs[0] = reflect.ValueOf(ctx)
+		return []reflect.Value{reflect.ValueOf(ctx)}, nil
 	}
+	return nil, nil
+}
 
-	var err error
-	results := a.method.Call(a.cacheArgs)
-	payload := results[0].Interface()
-	if e := results[1].Interface(); e != nil {
-		err = e.(error)
-	}
-	return payload, err
+func (a *simplePlainAdapter) call(args []reflect.Value) (interface{}, int, error) {
+	return results(a.method.Call(args))
 }
 
-func (a *simpleUnaryAdapter) invoke(ctx context.Context, w http.ResponseWriter, r *http.Request) (interface{}, error) {
-	data := reflect.New(a.argType.Elem()).Interface()
-	err := json.NewDecoder(r.Body).Decode(data)
+func (a *simpleUnaryAdapter) args(ctx context.Context, r *http.Request, opts DecodeOption) ([]reflect.Value, error) {
+	data, err := decodeRequest(r, a.argType, opts)
 	if err != nil {
 		return nil, err
 	}
+	return []reflect.Value{data}, nil
+}
+
+func (a *simpleUnaryAdapter) call(args []reflect.Value) (interface{}, int, error) {
+	return results(a.method.Call(args))
+}
+
+// checkCustomizedType panics if the customized type cannot be decoded from
+// the request unambiguously
+func checkCustomizedType(t reflect.Type) {
+	switch indirectType(t).Kind() {
+	case reflect.Interface, reflect.Func, reflect.Chan, reflect.UnsafePointer:
+		panic("unsupported customize type(" + t.String() + ")")
+	}
+}
 
-	a.cacheArgs[0] = reflect.ValueOf(data)
-	results := a.method.Call(a.cacheArgs)
-	payload := results[0].Interface()
-	if e := results[1].Interface(); e != nil {
+func indirectType(t reflect.Type) reflect.Type {
+	if t.Kind() == reflect.Ptr {
+		return t.Elem()
+	}
+	return t
+}
+
+// passAs converts the pointer to a fresh value to the argument of type t,
+// which is either the pointer itself or the value pointed to
+func passAs(v reflect.Value, t reflect.Type) reflect.Value {
+	if t.Kind() == reflect.Ptr {
+		return v
+	}
+	return v.Elem()
+}
+
+// decodeRequest decodes the JSON body into a fresh value of the customized
+// type, the values are passed by value and the pointers always point to a
+// value even if the body is empty
+func decodeRequest(r *http.Request, t reflect.Type, opts DecodeOption) (reflect.Value, error) {
+	v := reflect.New(indirectType(t))
+	if err := decodeJSON(r.Body, v.Interface(), opts); err != nil {
+		return reflect.Value{}, err
+	}
+	return passAs(v, t), nil
+}
+
+// results extracts the payload, the status code and the error from the
+// return values of the supported signatures:
+//
+//	func(...) error
+//	func(...) (Response, error)
+//	func(...) (Response, int, error)
+func results(values []reflect.Value) (interface{}, int, error) {
+	var (
+		payload    interface{}
+		statusCode int
+		err        error
+	)
+	switch len(values) {
+	case 2:
+		payload = values[0].Interface()
+	case 3:
+		payload = values[0].Interface()
+		statusCode = int(values[1].Int())
+	}
+	if e := values[len(values)-1].Interface(); e != nil {
 		err = e.(error)
 	}
-	return payload, err
+	return payload, statusCode, err
 }
@@ -0,0 +1,113 @@
+// Copyright 2026 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package fn
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"strings"
+)
+
+// ErrForbidden is returned (with status code 403) when the request doesn't
+// satisfy the requirements of handler
+var ErrForbidden = errors.New("forbidden")
+
+type (
+	// Authorizer decides whether the request satisfies the requirements of
+	// handler (see Require), it is called after all plugins have run, so
+	// that the principal set by the authentication plugins is available in
+	// the context. The errors without status code are responded with 403.
+	Authorizer interface {
+		Authorize(ctx context.Context, r *http.Request, requirements []string) error
+	}
+
+	// AuthorizerFunc is an adapter to use a function as Authorizer
+	AuthorizerFunc func(ctx context.Context, r *http.Request, requirements []string) error
+)
+
+var authorizer Authorizer
+
+// Authorize implements the Authorizer interface
+func (f AuthorizerFunc) Authorize(ctx context.Context, r *http.Request, requirements []string) error {
+	return f(ctx, r, requirements)
+}
+
+// SetAuthorizer sets the authorizer evaluating the requirements of all
+// handlers, the requests of handlers with requirements are rejected with 403
+// if there is no authorizer.
+func SetAuthorizer(a Authorizer) {
+	authorizer = a
+}
+
+// JWTScopes returns an authorizer requiring the scopes of the token verified
+// by JWTVerifier to contain all requirements, the scopes are read from the
+// space separated scope claim or the scp claim.
+func JWTScopes() Authorizer {
+	return AuthorizerFunc(func(ctx context.Context, r *http.Request, requirements []string) error {
+		claims, ok := JWTClaimsFromContext(ctx)
+		if !ok {
+			return ErrorWithStatusCode(ErrMissingToken, http.StatusUnauthorized)
+		}
+		var scopes struct {
+			Scope string      `json:"scope"`
+			Scp   interface{} `json:"scp"`
+		}
+		if err := claims.Decode(&scopes); err != nil {
+			return ErrForbidden
+		}
+
+		granted := map[string]bool{}
+		for _, scope := range strings.Fields(scopes.Scope) {
+			granted[scope] = true
+		}
+		switch scp := scopes.Scp.(type) {
+		case string:
+			for _, scope := range strings.Fields(scp) {
+				granted[scope] = true
+			}
+		case []interface{}:
+			for _, scope := range scp {
+				if s, ok := scope.(string); ok {
+					granted[s] = true
+				}
+			}
+		}
+		for _, requirement := range requirements {
+			if !granted[requirement] {
+				return ErrForbidden
+			}
+		}
+		return nil
+	})
+}
+
+// authorize evaluates the requirements of handler
+func (fn *fn) authorize(ctx context.Context, r *http.Request) error {
+	if len(fn.requirements) == 0 {
+		return nil
+	}
+	if authorizer == nil {
+		return ErrorWithStatusCode(ErrForbidden, http.StatusForbidden)
+	}
+	err := authorizer.Authorize(ctx, r, fn.requirements)
+	if err == nil {
+		return nil
+	}
+	if _, ok := UnwrapErrorStatusCode(err); ok {
+		return err
+	}
+	return ErrorWithStatusCode(err, http.StatusForbidden)
+}
@@ -0,0 +1,117 @@
+// Copyright 2026 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package fn
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestRequire(t *testing.T) {
+	SetErrorEncoder(func(ctx context.Context, err error) interface{} {
+		return err.Error()
+	})
+	defer SetAuthorizer(nil)
+
+	authenticate := func(ctx context.Context, r *http.Request) (context.Context, error) {
+		return WithPrincipal(ctx, r.Header.Get("X-User")), nil
+	}
+	permissions := map[string][]string{
+		"alice": {"orders:read", "orders:write"},
+		"bob":   {"orders:read"},
+	}
+
+	var evaluated []string
+	group := NewGroup().Plugin(authenticate).Require("orders:read")
+	read := group.Wrap(func() error { return nil })
+	write := group.Wrap(func() error { return nil }).Require("orders:write")
+	public := Wrap(func() error { return nil })
+	require.Equal(t, []string{"orders:read"}, read.Requirements())
+	require.Equal(t, []string{"orders:read", "orders:write"}, write.Requirements())
+	require.Empty(t, public.Requirements())
+
+	serve := func(handler http.Handler, user string) *httptest.ResponseRecorder {
+		request := httptest.NewRequest(http.MethodGet, "/", nil)
+		request.Header.Set("X-User", user)
+		recorder := httptest.NewRecorder()
+		handler.ServeHTTP(recorder, request)
+		return recorder
+	}
+
+	// Rejected without authorizer
+	require.Equal(t, http.StatusForbidden, serve(read, "alice").Code)
+	require.Equal(t, http.StatusNoContent, serve(public, "alice").Code)
+
+	SetAuthorizer(AuthorizerFunc(func(ctx context.Context, r *http.Request, requirements []string) error {
+		evaluated = requirements
+		principal, ok := PrincipalFromContext(ctx)
+		if !ok {
+			return ErrorWithStatusCode(errors.New("unauthenticated"), http.StatusUnauthorized)
+		}
+		for _, requirement := range requirements {
+			granted := false
+			for _, permission := range permissions[principal] {
+				granted = granted || permission == requirement
+			}
+			if !granted {
+				return errors.New("missing permission " + requirement)
+			}
+		}
+		return nil
+	}))
+
+	require.Equal(t, http.StatusNoContent, serve(write, "alice").Code)
+	require.Equal(t, []string{"orders:read", "orders:write"}, evaluated)
+	require.Equal(t, http.StatusNoContent, serve(read, "bob").Code)
+
+	recorder := serve(write, "bob")
+	require.Equal(t, http.StatusForbidden, recorder.Code)
+	require.Equal(t, `"missing permission orders:write"`+"\n", recorder.Body.String())
+	require.Equal(t, http.StatusUnauthorized, serve(read, "").Code)
+
+	// The requirements apply to JSON-RPC
+	rpc := NewJSONRPC().Register("write", write)
+	request := httptest.NewRequest(http.MethodPost, "/rpc", strings.NewReader(`{"jsonrpc":"2.0","method":"write","id":1}`))
+	request.Header.Set("X-User", "bob")
+	recorder = httptest.NewRecorder()
+	rpc.ServeHTTP(recorder, request)
+	require.Contains(t, recorder.Body.String(), `"code":403`)
+}
+
+func TestJWTScopes(t *testing.T) {
+	authorizer := JWTScopes()
+	check := func(payload string, requirements ...string) error {
+		ctx := context.Background()
+		if payload != "" {
+			claims, err := parseClaims([]byte(payload))
+			require.NoError(t, err)
+			ctx = context.WithValue(ctx, jwtClaimsKey{}, claims)
+		}
+		return authorizer.Authorize(ctx, nil, requirements)
+	}
+
+	require.NoError(t, check(`{"scope":"orders:read orders:write"}`, "orders:read", "orders:write"))
+	require.NoError(t, check(`{"scp":["orders:read"]}`, "orders:read"))
+	require.NoError(t, check(`{"scp":"orders:read users:read"}`, "users:read"))
+	require.Equal(t, ErrForbidden, check(`{"scope":"orders:read"}`, "orders:write"))
+	code, _ := UnwrapErrorStatusCode(check("", "orders:read"))
+	require.Equal(t, http.StatusUnauthorized, code)
+}
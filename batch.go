@@ -0,0 +1,265 @@
+// Copyright 2026 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package fn
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"sync"
+)
+
+const (
+	defaultBatchMaxItems    = 20
+	defaultBatchConcurrency = 4
+)
+
+var (
+	// ErrTooManyBatchItems is returned (with status code 413) when the batch
+	// contains more items than the limit
+	ErrTooManyBatchItems = errors.New("too many batch items")
+
+	// ErrNestedBatch is returned (with status code 400) when a batch item
+	// targets a batch endpoint
+	ErrNestedBatch = errors.New("nested batch is not allowed")
+)
+
+type (
+	// BatchHandler executes multiple sub-requests in one request, each of
+	// them is dispatched to the routes as an individual request, so that it
+	// runs through the plugins, decoding and encoders of the target handler.
+	BatchHandler struct {
+		routes      http.Handler
+		maxItems    int
+		concurrency int
+	}
+
+	// BatchRequest represents a sub-request of batch
+	BatchRequest struct {
+		Method  string            `json:"method"`
+		Path    string            `json:"path"`
+		Headers map[string]string `json:"headers,omitempty"`
+		Body    json.RawMessage   `json:"body,omitempty"`
+	}
+
+	// BatchResponse represents the result of a sub-request, the body is
+	// embedded as is if it is JSON, otherwise as a string
+	BatchResponse struct {
+		Status  int               `json:"status"`
+		Headers map[string]string `json:"headers,omitempty"`
+		Body    json.RawMessage   `json:"body,omitempty"`
+	}
+
+	batchKey struct{}
+
+	// batchRecorder records the response of a sub-request
+	batchRecorder struct {
+		header http.Header
+		status int
+		body   bytes.Buffer
+	}
+)
+
+// batchDroppedHeaders are the headers of batch request not inherited by the
+// sub-requests, which describe the body of batch request, or apply to the
+// batch request as a whole, e.g. the validators and the idempotency key
+var batchDroppedHeaders = map[string]bool{
+	"Content-Length":      true,
+	"Content-Encoding":    true,
+	"Accept-Encoding":     true,
+	"If-Match":            true,
+	"If-None-Match":       true,
+	"If-Modified-Since":   true,
+	"If-Unmodified-Since": true,
+	"If-Range":            true,
+	"Range":               true,
+	"Idempotency-Key":     true,
+}
+
+// Batch returns a handler which dispatches the sub-requests to the routes
+// (e.g. a http.ServeMux) with bounded parallelism.
+//
+//	POST /batch
+//	[{"method":"GET","path":"/user/balance"},{"method":"POST","path":"/user/buy","body":{"id":1}}]
+//
+//	[{"status":200,"headers":{...},"body":{"balance":100}},{"status":400,"headers":{...},"body":"please check balance"}]
+func Batch(routes http.Handler) *BatchHandler {
+	return &BatchHandler{
+		routes:      routes,
+		maxItems:    defaultBatchMaxItems,
+		concurrency: defaultBatchConcurrency,
+	}
+}
+
+// MaxItems limits the number of sub-requests of a batch, the larger batches
+// are rejected with 413
+func (b *BatchHandler) MaxItems(n int) *BatchHandler {
+	b.maxItems = n
+	return b
+}
+
+// Concurrency limits the number of sub-requests executed in parallel
+func (b *BatchHandler) Concurrency(n int) *BatchHandler {
+	if n < 1 {
+		n = 1
+	}
+	b.concurrency = n
+	return b
+}
+
+func (b *BatchHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if handleCORS(nil, w, r) {
+		return
+	}
+	w.Header().Set("Content-Type", "application/json; charset=utf-8")
+	r = withRequestID(w, r)
+	ctx := r.Context()
+	if ctx.Value(batchKey{}) != nil {
+		failure(ctx, w, ErrorWithStatusCode(ErrNestedBatch, http.StatusBadRequest))
+		return
+	}
+	limit := effectiveLimit(0, bodyLimit)
+	if err := limitBody(w, r, limit); err != nil {
+		failure(ctx, w, err)
+		return
+	}
+	if c := globalCompression; c != nil {
+		if err := decompressRequest(r, c, limit); err != nil {
+			failure(ctx, w, err)
+			return
+		}
+		// The response is compressed as a whole, the items don't inherit
+		// Accept-Encoding
+		w = newCompressWriter(w, r, c)
+		if cw, ok := w.(*compressWriter); ok {
+			defer cw.close()
+		}
+	}
+
+	var requests []BatchRequest
+	if err := decodeJSON(r.Body, &requests, 0); err != nil {
+		failure(ctx, w, err)
+		return
+	}
+	if len(requests) > b.maxItems {
+		failure(ctx, w, ErrorWithStatusCode(ErrTooManyBatchItems, http.StatusRequestEntityTooLarge))
+		return
+	}
+
+	ctx = context.WithValue(ctx, batchKey{}, true)
+	responses := make([]*BatchResponse, len(requests))
+	sem := make(chan struct{}, b.concurrency)
+	wg := sync.WaitGroup{}
+	for i := range requests {
+		sem <- struct{}{}
+		wg.Add(1)
+		go func(i int) {
+			defer func() {
+				<-sem
+				wg.Done()
+			}()
+			responses[i] = b.dispatch(ctx, r, &requests[i])
+		}(i)
+	}
+	wg.Wait()
+
+	_ = json.NewEncoder(w).Encode(responses)
+}
+
+// dispatch serves a sub-request, the failures (including panics) are
+// isolated to the response of the sub-request
+func (b *BatchHandler) dispatch(ctx context.Context, parent *http.Request, item *BatchRequest) (resp *BatchResponse) {
+	defer func() {
+		if err := recover(); err != nil {
+			resp = batchError(http.StatusInternalServerError, fmt.Sprint(err))
+		}
+	}()
+
+	method := strings.ToUpper(item.Method)
+	if method == "" {
+		method = http.MethodGet
+	}
+	if !strings.HasPrefix(item.Path, "/") {
+		return batchError(http.StatusBadRequest, "invalid path: "+item.Path)
+	}
+
+	r, err := http.NewRequest(method, item.Path, bytes.NewReader(item.Body))
+	if err != nil {
+		return batchError(http.StatusBadRequest, err.Error())
+	}
+	r = r.WithContext(ctx)
+
+	// Inherit the headers (e.g. credentials) and the client information of
+	// the batch request, the sub-requests can still set the dropped ones
+	for key, values := range parent.Header {
+		if !batchDroppedHeaders[key] {
+			r.Header[key] = append([]string(nil), values...)
+		}
+	}
+	for key, value := range item.Headers {
+		r.Header.Set(key, value)
+	}
+	r.RemoteAddr = parent.RemoteAddr
+	r.Host = parent.Host
+	r.TLS = parent.TLS
+
+	recorder := &batchRecorder{header: http.Header{}}
+	b.routes.ServeHTTP(recorder, r)
+	return recorder.result()
+}
+
+func (rec *batchRecorder) Header() http.Header {
+	return rec.header
+}
+
+func (rec *batchRecorder) Write(p []byte) (int, error) {
+	if rec.status == 0 {
+		rec.status = http.StatusOK
+	}
+	return rec.body.Write(p)
+}
+
+func (rec *batchRecorder) WriteHeader(statusCode int) {
+	if rec.status == 0 {
+		rec.status = statusCode
+	}
+}
+
+func (rec *batchRecorder) result() *BatchResponse {
+	resp := &BatchResponse{Status: rec.status, Headers: map[string]string{}}
+	if resp.Status == 0 {
+		resp.Status = http.StatusOK
+	}
+	for key, values := range rec.header {
+		resp.Headers[key] = strings.Join(values, ", ")
+	}
+	if body := bytes.TrimSpace(rec.body.Bytes()); len(body) > 0 {
+		if json.Valid(body) {
+			resp.Body = body
+		} else {
+			resp.Body, _ = json.Marshal(string(body))
+		}
+	}
+	return resp
+}
+
+func batchError(status int, message string) *BatchResponse {
+	body, _ := json.Marshal(message)
+	return &BatchResponse{Status: status, Body: body}
+}
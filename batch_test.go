@@ -0,0 +1,164 @@
+// Copyright 2026 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package fn
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestBatch(t *testing.T) {
+	SetErrorEncoder(func(ctx context.Context, err error) interface{} {
+		return err.Error()
+	})
+	SetResponseEncoder(func(ctx context.Context, payload interface{}) interface{} {
+		return payload
+	})
+
+	var running, maxRunning int32
+	mux := http.NewServeMux()
+	mux.Handle("/echo", Wrap(func(header http.Header, req *testRequest) (*testResponse, error) {
+		n := atomic.AddInt32(&running, 1)
+		defer atomic.AddInt32(&running, -1)
+		for {
+			max := atomic.LoadInt32(&maxRunning)
+			if n <= max || atomic.CompareAndSwapInt32(&maxRunning, max, n) {
+				break
+			}
+		}
+		time.Sleep(10 * time.Millisecond)
+		return &testResponse{Code: req.Bar, Message: req.Foo + header.Get("X-Token")}, nil
+	}))
+	mux.Handle("/fail", Wrap(func() error {
+		return ErrorWithStatusCode(errors.New("conflict"), http.StatusConflict)
+	}))
+	mux.Handle("/panic", Wrap(func() error {
+		panic("boom")
+	}))
+	batch := Batch(mux).Concurrency(2).MaxItems(8)
+	mux.Handle("/batch", batch)
+
+	body := `[
+		{"method":"POST","path":"/echo","body":{"foo":"a","bar":1}},
+		{"method":"POST","path":"/echo","headers":{"X-Token":"-override"},"body":{"foo":"b"}},
+		{"method":"POST","path":"/echo","body":{"foo":"c"}},
+		{"method":"POST","path":"/fail"},
+		{"path":"/panic"},
+		{"path":"/missing"},
+		{"path":"/batch"},
+		{"path":"echo"}
+	]`
+	recorder := httptest.NewRecorder()
+	request := httptest.NewRequest(http.MethodPost, "/batch", strings.NewReader(body))
+	request.Header.Set("X-Token", "-inherited")
+	mux.ServeHTTP(recorder, request)
+	require.Equal(t, http.StatusOK, recorder.Code)
+
+	var responses []BatchResponse
+	require.NoError(t, json.Unmarshal(recorder.Body.Bytes(), &responses))
+	require.Len(t, responses, 8)
+
+	expects := []struct {
+		status int
+		body   string
+	}{
+		{http.StatusOK, `{"code":1,"message":"a-inherited"}`},
+		{http.StatusOK, `{"code":0,"message":"b-override"}`},
+		{http.StatusOK, `{"code":0,"message":"c-inherited"}`},
+		{http.StatusConflict, `"conflict"`},
+		{http.StatusInternalServerError, `"boom"`},
+		{http.StatusNotFound, `"404 page not found"`},
+		{http.StatusBadRequest, `"nested batch is not allowed"`},
+		{http.StatusBadRequest, `"invalid path: echo"`},
+	}
+	for i, expect := range expects {
+		require.Equal(t, expect.status, responses[i].Status, "item %d", i)
+		require.JSONEq(t, expect.body, string(responses[i].Body), "item %d", i)
+	}
+	require.Equal(t, "application/json; charset=utf-8", responses[0].Headers["Content-Type"])
+	require.Equal(t, int32(2), atomic.LoadInt32(&maxRunning))
+
+	recorder = httptest.NewRecorder()
+	mux.ServeHTTP(recorder, httptest.NewRequest(http.MethodPost, "/batch", strings.NewReader(`[{},{},{},{},{},{},{},{},{}]`)))
+	require.Equal(t, http.StatusRequestEntityTooLarge, recorder.Code)
+
+	recorder = httptest.NewRecorder()
+	mux.ServeHTTP(recorder, httptest.NewRequest(http.MethodPost, "/batch", strings.NewReader(`{}`)))
+	require.Equal(t, http.StatusBadRequest, recorder.Code)
+}
+
+func TestBatchInheritedHeaders(t *testing.T) {
+	SetErrorEncoder(func(ctx context.Context, err error) interface{} {
+		return err.Error()
+	})
+	SetResponseEncoder(func(ctx context.Context, payload interface{}) interface{} {
+		return payload
+	})
+	SetCompression(&CompressionOptions{MinSize: 10})
+	defer SetCompression(nil)
+	SetIdempotencyStore(NewMemoryIdempotencyStore())
+	defer SetIdempotencyStore(NewMemoryIdempotencyStore())
+
+	mux := http.NewServeMux()
+	mux.Handle("/echo", Wrap(func(header http.Header, req *testRequest) (*testResponse, error) {
+		return &testResponse{Message: req.Foo + header.Get("X-Token")}, nil
+	}).ETag(&ETagOptions{}).Idempotency(&IdempotencyOptions{}))
+	mux.Handle("/batch", Batch(mux))
+
+	body := `[
+		{"method":"POST","path":"/echo","body":{"foo":"a"}},
+		{"method":"POST","path":"/echo","body":{"foo":"b"}},
+		{"method":"GET","path":"/echo","body":{"foo":"c"}},
+		{"method":"GET","path":"/echo","headers":{"Accept-Encoding":"gzip"},"body":{"foo":"d"}}
+	]`
+	recorder := httptest.NewRecorder()
+	request := httptest.NewRequest(http.MethodPost, "/batch", bytes.NewReader(gzipBytes(t, body)))
+	request.Header.Set("X-Token", "-inherited")
+	request.Header.Set("Content-Encoding", "gzip")
+	request.Header.Set("Accept-Encoding", "gzip")
+	request.Header.Set("If-None-Match", "*")
+	request.Header.Set("If-Match", `"batch"`)
+	request.Header.Set(IdempotencyKeyHeader, "batch")
+	mux.ServeHTTP(recorder, request)
+	require.Equal(t, http.StatusOK, recorder.Code)
+
+	// The batch request is decompressed, and the response is compressed as a
+	// whole
+	require.Equal(t, "gzip", recorder.Header().Get("Content-Encoding"))
+	zr, err := gzip.NewReader(recorder.Body)
+	require.NoError(t, err)
+	var responses []BatchResponse
+	require.NoError(t, json.NewDecoder(zr).Decode(&responses))
+	require.Len(t, responses, 4)
+	for i, foo := range []string{"a", "b", "c"} {
+		require.Equal(t, http.StatusOK, responses[i].Status, "item %d", i)
+		require.Empty(t, responses[i].Headers["Content-Encoding"], "item %d", i)
+		require.JSONEq(t, `{"code":0,"message":"`+foo+`-inherited"}`, string(responses[i].Body), "item %d", i)
+	}
+	// Set by the sub-request explicitly
+	require.Equal(t, http.StatusOK, responses[3].Status)
+	require.Equal(t, "gzip", responses[3].Headers["Content-Encoding"])
+}
@@ -0,0 +1,152 @@
+// Copyright 2026 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package fn
+
+import (
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+)
+
+// DecodeOption controls how the JSON request body is decoded
+type DecodeOption uint
+
+const (
+	// DisallowUnknownFields rejects the objects containing fields which do
+	// not match any field of the request type
+	DisallowUnknownFields DecodeOption = 1 << iota
+	// UseNumber decodes numbers into json.Number instead of float64 when
+	// the destination is interface{}
+	UseNumber
+	// DisallowTrailingData rejects the bodies containing data after the
+	// JSON value
+	DisallowTrailingData
+	// AllowEmptyBody treats the empty body as the zero value request
+	// instead of rejecting it with EOF
+	AllowEmptyBody
+)
+
+var (
+	// ErrRequestBodyTooLarge is returned (with status code 413) when the
+	// request body exceeds the body limit
+	ErrRequestBodyTooLarge = errors.New("request body too large")
+
+	errTrailingData = errors.New("unexpected data after top-level JSON value")
+)
+
+var (
+	// Global request body limit, zero or negative means unlimited
+	bodyLimit int64
+
+	// Global decode options
+	decodeOptions DecodeOption
+)
+
+// limitedBody reports the oversize body as ErrRequestBodyTooLarge with
+// status code 413, the underlying http.MaxBytesReader tells the server to
+// close the connection after the response
+type limitedBody struct {
+	io.ReadCloser
+	limit int64
+	read  int64
+}
+
+func newLimitedBody(w http.ResponseWriter, body io.ReadCloser, limit int64) *limitedBody {
+	return &limitedBody{
+		ReadCloser: http.MaxBytesReader(w, body, limit),
+		limit:      limit,
+	}
+}
+
+func (b *limitedBody) Read(p []byte) (int, error) {
+	n, err := b.ReadCloser.Read(p)
+	b.read += int64(n)
+	if err != nil && err != io.EOF && b.read >= b.limit {
+		err = ErrorWithStatusCode(ErrRequestBodyTooLarge, http.StatusRequestEntityTooLarge)
+	}
+	return n, err
+}
+
+// effectiveLimit resolves the limit of handler, zero means inheriting the
+// global one and negative means unlimited
+func effectiveLimit(limit, global int64) int64 {
+	if limit == 0 {
+		limit = global
+	}
+	if limit < 0 {
+		return 0
+	}
+	return limit
+}
+
+// limitBody applies the body limit to the request, the requests declaring
+// an oversize Content-Length are rejected without reading the body
+func limitBody(w http.ResponseWriter, r *http.Request, limit int64) error {
+	if limit <= 0 || r.Body == nil || r.Body == http.NoBody {
+		return nil
+	}
+	if r.ContentLength > limit {
+		return ErrorWithStatusCode(ErrRequestBodyTooLarge, http.StatusRequestEntityTooLarge)
+	}
+	r.Body = newLimitedBody(w, r.Body, limit)
+	return nil
+}
+
+func makeDecodeOption(opts []DecodeOption) *DecodeOption {
+	var o DecodeOption
+	for _, opt := range opts {
+		o |= opt
+	}
+	return &o
+}
+
+// effectiveDecodeOption resolves the decode options of handler, nil means
+// inheriting the global ones
+func effectiveDecodeOption(opt *DecodeOption) DecodeOption {
+	if opt == nil {
+		return decodeOptions
+	}
+	return *opt
+}
+
+// decodeJSON decodes the request body into v according to the options
+func decodeJSON(body io.Reader, v interface{}, opts DecodeOption) error {
+	if body == nil {
+		body = http.NoBody
+	}
+	dec := json.NewDecoder(body)
+	if opts&DisallowUnknownFields != 0 {
+		dec.DisallowUnknownFields()
+	}
+	if opts&UseNumber != 0 {
+		dec.UseNumber()
+	}
+	if err := dec.Decode(v); err != nil {
+		if err == io.EOF && opts&AllowEmptyBody != 0 {
+			return nil
+		}
+		return err
+	}
+	if opts&DisallowTrailingData != 0 {
+		if _, err := dec.Token(); err != io.EOF {
+			if err == nil {
+				err = errTrailingData
+			}
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,109 @@
+// Copyright 2026 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package fn
+
+import (
+	"bytes"
+	"encoding/json"
+	"io/ioutil"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func serveBody(handler http.Handler, contentType string, body []byte, contentLength bool) *httptest.ResponseRecorder {
+	recorder := httptest.NewRecorder()
+	request := httptest.NewRequest(http.MethodPost, "/", nil)
+	request.Header.Set("Content-Type", contentType)
+	request.Body = ioutil.NopCloser(bytes.NewReader(body))
+	request.ContentLength = -1
+	if contentLength {
+		request.ContentLength = int64(len(body))
+	}
+	handler.ServeHTTP(recorder, request)
+	return recorder
+}
+
+func TestBodyLimit(t *testing.T) {
+	SetRequestBodyLimit(64)
+	defer SetRequestBodyLimit(0)
+
+	small := []byte(`{"foo":"hello"}`)
+	large := []byte(`{"foo":"` + strings.Repeat("a", 128) + `"}`)
+
+	global := Wrap(withReq)
+	require.Equal(t, http.StatusOK, serveBody(global, "application/json", small, false).Code)
+	require.Equal(t, http.StatusRequestEntityTooLarge, serveBody(global, "application/json", large, false).Code)
+	require.Equal(t, http.StatusRequestEntityTooLarge, serveBody(global, "application/json", large, true).Code)
+
+	group := NewGroup().BodyLimit(256)
+	require.Equal(t, http.StatusOK, serveBody(group.Wrap(withReq), "application/json", large, false).Code)
+	require.Equal(t, http.StatusRequestEntityTooLarge, serveBody(group.Wrap(withReq).BodyLimit(8), "application/json", small, false).Code)
+	require.Equal(t, http.StatusOK, serveBody(Wrap(withReq).BodyLimit(-1), "application/json", large, false).Code)
+
+	form := []byte("foo=" + strings.Repeat("a", 128))
+	require.Equal(t, http.StatusRequestEntityTooLarge, serveBody(Wrap(withForm), "application/x-www-form-urlencoded", form, false).Code)
+	require.Equal(t, http.StatusRequestEntityTooLarge, serveBody(Wrap(withPostFormPtr), "application/x-www-form-urlencoded", form, false).Code)
+	require.Equal(t, http.StatusOK, serveBody(Wrap(withForm).BodyLimit(256), "application/x-www-form-urlencoded", form, false).Code)
+
+	body := &bytes.Buffer{}
+	writer := multipart.NewWriter(body)
+	require.NoError(t, writer.WriteField("foo", strings.Repeat("a", 128)))
+	require.NoError(t, writer.Close())
+	multipartBody := body.Bytes()
+	require.Equal(t, http.StatusRequestEntityTooLarge, serveBody(Wrap(withMultipartForm), writer.FormDataContentType(), multipartBody, false).Code)
+	require.Equal(t, http.StatusOK, serveBody(Wrap(withMultipartForm).BodyLimit(1024), writer.FormDataContentType(), multipartBody, false).Code)
+}
+
+func TestDecodeOptions(t *testing.T) {
+	var payload map[string]interface{}
+	handler := func(req *testRequest) (*testResponse, error) {
+		return &testResponse{Code: req.Bar, Message: req.Foo}, nil
+	}
+	dynamic := func(req *map[string]interface{}) (*testResponse, error) {
+		payload = *req
+		return successResponse, nil
+	}
+
+	unknown := []byte(`{"foo":"hello","baz":1}`)
+	trailing := []byte(`{"foo":"hello"} {"foo":"world"}`)
+	empty := []byte(" \n")
+
+	// default options are lenient, except for the empty body
+	require.Equal(t, http.StatusOK, serveBody(Wrap(handler), "application/json", unknown, false).Code)
+	require.Equal(t, http.StatusOK, serveBody(Wrap(handler), "application/json", trailing, false).Code)
+	require.Equal(t, http.StatusBadRequest, serveBody(Wrap(handler), "application/json", empty, false).Code)
+
+	strict := Wrap(handler).DecodeOptions(DisallowUnknownFields, DisallowTrailingData)
+	require.Equal(t, http.StatusBadRequest, serveBody(strict, "application/json", unknown, false).Code)
+	require.Equal(t, http.StatusBadRequest, serveBody(strict, "application/json", trailing, false).Code)
+	require.Equal(t, http.StatusOK, serveBody(strict, "application/json", []byte(`{"foo":"hello"} `), false).Code)
+
+	optional := NewGroup().DecodeOptions(AllowEmptyBody).Wrap(handler)
+	recorder := serveBody(optional, "application/json", empty, false)
+	require.Equal(t, http.StatusOK, recorder.Code)
+	require.JSONEq(t, `{"code":0,"message":""}`, recorder.Body.String())
+
+	SetDecodeOptions(UseNumber, DisallowTrailingData)
+	defer SetDecodeOptions()
+	require.Equal(t, http.StatusOK, serveBody(Wrap(dynamic), "application/json", []byte(`{"n":12345678901234567890}`), false).Code)
+	require.Equal(t, json.Number("12345678901234567890"), payload["n"])
+	require.Equal(t, http.StatusBadRequest, serveBody(Wrap(handler), "application/json", trailing, false).Code)
+	require.Equal(t, http.StatusOK, serveBody(Wrap(handler).DecodeOptions(), "application/json", trailing, false).Code)
+}
@@ -0,0 +1,426 @@
+// Copyright 2026 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package fn
+
+import (
+	"container/list"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"net/http"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	defaultCacheTTL  = time.Minute
+	defaultCacheSize = 64 << 20
+)
+
+type (
+	// CacheOptions configures caching the successful responses of GET, the
+	// HEAD requests are served from the responses of GET
+	CacheOptions struct {
+		// TTL is how long the responses are cached, 1 minute if zero
+		TTL time.Duration
+		// Query are the query parameters distinguishing the responses, all
+		// parameters if empty
+		Query []string
+		// Vary are the request headers distinguishing the responses, which
+		// are also added to the Vary header of responses
+		Vary []string
+		// Tags are the tags of the cached responses for invalidation, the
+		// handlers can add more by CacheTags
+		Tags []string
+	}
+
+	// CachedResponse is a response in the cache
+	CachedResponse struct {
+		StatusCode int
+		Header     http.Header
+		Body       []byte
+		Tags       []string
+		StoredAt   time.Time
+	}
+
+	// CacheStore stores the cached responses, the in-memory store is used
+	// by default, a shared store (e.g. Redis) is required to share the
+	// responses and invalidations across instances.
+	CacheStore interface {
+		// Get returns the response of key, or nil if it is missing or
+		// expired
+		Get(ctx context.Context, key string) (*CachedResponse, error)
+		// Set stores the response of key for ttl
+		Set(ctx context.Context, key string, resp *CachedResponse, ttl time.Duration) error
+		// Invalidate removes the responses with any of the tags
+		Invalidate(ctx context.Context, tags ...string) error
+	}
+
+	// MemoryCacheStore is a CacheStore in memory, the least recently used
+	// responses are evicted when the size exceeds the limit
+	MemoryCacheStore struct {
+		mu      sync.Mutex
+		maxSize int64
+		size    int64
+		lru     *list.List
+		entries map[string]*list.Element
+		tags    map[string]map[string]struct{}
+		now     func() time.Time
+	}
+
+	memoryCacheEntry struct {
+		key     string
+		resp    *CachedResponse
+		size    int64
+		expires time.Time
+	}
+
+	// cacheRecorder records the response to be cached
+	cacheRecorder struct {
+		http.ResponseWriter
+		before http.Header
+		status int
+		header http.Header
+		body   []byte
+	}
+
+	cacheTagsKey struct{}
+)
+
+var cacheStore CacheStore = NewMemoryCacheStore(defaultCacheSize)
+
+// SetCacheStore sets where the responses are cached, an in-memory store of
+// 64MB by default
+func SetCacheStore(store CacheStore) {
+	cacheStore = store
+}
+
+// InvalidateCache removes the cached responses with any of the tags, e.g.
+// the handlers of updates invalidate the responses of queries
+func InvalidateCache(ctx context.Context, tags ...string) error {
+	return cacheStore.Invalidate(ctx, tags...)
+}
+
+// CacheTags adds the tags to the response being cached, e.g. the id of the
+// resource, it does nothing if the handler is not cached
+func CacheTags(ctx context.Context, tags ...string) {
+	if p, ok := ctx.Value(cacheTagsKey{}).(*[]string); ok {
+		*p = append(*p, tags...)
+	}
+}
+
+// lookupCache returns the cached response of request, or the recorder which
+// caches the response if the request is cacheable. The requests with
+// Cache-Control no-cache skip the lookup, and no-store disables caching.
+func lookupCache(ctx context.Context, opts *CacheOptions, w http.ResponseWriter, r *http.Request) (context.Context, *CachedResponse, *cacheRecorder) {
+	addVary(w.Header(), opts.Vary...)
+	if r.Method != http.MethodGet && r.Method != http.MethodHead {
+		return ctx, nil, nil
+	}
+	directives := cacheControl(r.Header)
+	if directives["no-store"] {
+		return ctx, nil, nil
+	}
+
+	key := cacheKey(ctx, opts, r)
+	if !directives["no-cache"] {
+		// The cache is best effort, the errors of store are ignored
+		if resp, err := cacheStore.Get(ctx, key); err == nil && resp != nil {
+			return ctx, resp, nil
+		}
+	}
+	if r.Method == http.MethodHead {
+		return ctx, nil, nil
+	}
+
+	tags := append([]string(nil), opts.Tags...)
+	ctx = context.WithValue(ctx, cacheTagsKey{}, &tags)
+	return ctx, nil, newCacheRecorder(w)
+}
+
+func newCacheRecorder(w http.ResponseWriter) *cacheRecorder {
+	return &cacheRecorder{ResponseWriter: w, before: cloneHeader(w.Header())}
+}
+
+// cacheKey identifies the response by the method, path, selected query
+// parameters, Vary headers and the principal (or the credentials)
+func cacheKey(ctx context.Context, opts *CacheOptions, r *http.Request) string {
+	var b strings.Builder
+	b.WriteString(r.URL.Path)
+	b.WriteByte('\n')
+	query := r.URL.Query()
+	if len(opts.Query) > 0 {
+		selected := url.Values{}
+		for _, k := range opts.Query {
+			if v, ok := query[k]; ok {
+				selected[k] = v
+			}
+		}
+		query = selected
+	}
+	b.WriteString(query.Encode())
+	b.WriteByte('\n')
+
+	vary := append([]string(nil), opts.Vary...)
+	sort.Strings(vary)
+	for _, h := range vary {
+		b.WriteString(http.CanonicalHeaderKey(h))
+		b.WriteByte(':')
+		b.WriteString(strings.Join(r.Header[http.CanonicalHeaderKey(h)], ","))
+		b.WriteByte('\n')
+	}
+
+	// The responses of different users are never shared, the credentials
+	// identify the user if no plugin sets the principal
+	if principal, ok := PrincipalFromContext(ctx); ok {
+		b.WriteString(principal)
+	} else {
+		for _, h := range []string{"Authorization", "Cookie"} {
+			b.WriteString(h)
+			b.WriteByte(':')
+			b.WriteString(strings.Join(r.Header[h], ","))
+			b.WriteByte('\n')
+		}
+	}
+	sum := sha256.Sum256([]byte(b.String()))
+	return "GET " + hex.EncodeToString(sum[:])
+}
+
+// cacheControl parses the directives of Cache-Control header
+func cacheControl(header http.Header) map[string]bool {
+	directives := map[string]bool{}
+	for _, v := range header["Cache-Control"] {
+		for _, d := range strings.Split(v, ",") {
+			d = strings.ToLower(strings.TrimSpace(d))
+			if d == "max-age=0" {
+				d = "no-cache"
+			}
+			directives[d] = true
+		}
+	}
+	if len(directives) == 0 && header.Get("Pragma") == "no-cache" {
+		directives["no-cache"] = true
+	}
+	return directives
+}
+
+// writeCached writes the cached response
+func writeCached(w http.ResponseWriter, r *http.Request, resp *CachedResponse) error {
+	return writeRecorded(w, r, resp, http.Header{
+		"Age": {strconv.Itoa(int(time.Since(resp.StoredAt) / time.Second))},
+	})
+}
+
+// writeRecorded writes the recorded response with the extra headers, the
+// conditional requests are evaluated against its validators unless r is nil,
+// and the failed preconditions are returned as the error without writing
+// anything
+func writeRecorded(w http.ResponseWriter, r *http.Request, resp *CachedResponse, extra http.Header) error {
+	status := 0
+	if r != nil {
+		lastModified, _ := http.ParseTime(resp.Header.Get("Last-Modified"))
+		status = evaluatePreconditions(r, resp.Header.Get("ETag"), lastModified)
+		if status == http.StatusPreconditionFailed {
+			return ErrorWithStatusCode(ErrPreconditionFailed, http.StatusPreconditionFailed)
+		}
+	}
+
+	header := w.Header()
+	for k, v := range resp.Header {
+		header[k] = append([]string(nil), v...)
+	}
+	for k, v := range extra {
+		header[k] = v
+	}
+	if status == http.StatusNotModified {
+		header.Del("Content-Type")
+		w.WriteHeader(http.StatusNotModified)
+		return nil
+	}
+	if resp.StatusCode != 0 {
+		w.WriteHeader(resp.StatusCode)
+	}
+	if r == nil || r.Method != http.MethodHead {
+		_, _ = w.Write(resp.Body)
+	}
+	return nil
+}
+
+func (rec *cacheRecorder) WriteHeader(statusCode int) {
+	if rec.status == 0 {
+		rec.status = statusCode
+		// The header is captured before the underlying writers (e.g. the
+		// compression) modify it
+		rec.header = cloneHeader(rec.ResponseWriter.Header())
+	}
+	rec.ResponseWriter.WriteHeader(statusCode)
+}
+
+func (rec *cacheRecorder) Write(p []byte) (int, error) {
+	if rec.status == 0 {
+		rec.WriteHeader(http.StatusOK)
+	}
+	rec.body = append(rec.body, p...)
+	return rec.ResponseWriter.Write(p)
+}
+
+// store caches the response if it is successful and cacheable
+func (rec *cacheRecorder) store(ctx context.Context, r *http.Request, opts *CacheOptions, start time.Time) {
+	if rec.status != http.StatusOK || rec.header == nil || rec.header.Get("Set-Cookie") != "" {
+		return
+	}
+	directives := cacheControl(http.Header{"Cache-Control": rec.header["Cache-Control"]})
+	if directives["no-store"] || directives["private"] {
+		return
+	}
+
+	var tags []string
+	if p, ok := ctx.Value(cacheTagsKey{}).(*[]string); ok {
+		tags = *p
+	}
+	ttl := opts.TTL
+	if ttl <= 0 {
+		ttl = defaultCacheTTL
+	}
+	resp := rec.recorded(start)
+	resp.Tags = tags
+	_ = cacheStore.Set(ctx, cacheKey(ctx, opts, r), resp, ttl)
+}
+
+// recorded returns the recorded response, only the headers set by the
+// handler and the encoding are kept, the ones set before (e.g. the request
+// id, CORS and rate limit headers) belong to the exchange rather than the
+// response
+func (rec *cacheRecorder) recorded(start time.Time) *CachedResponse {
+	header := http.Header{}
+	for k, v := range rec.header {
+		if k == "Content-Type" || strings.Join(v, ",") != strings.Join(rec.before[k], ",") {
+			header[k] = v
+		}
+	}
+	return &CachedResponse{
+		StatusCode: rec.status,
+		Header:     header,
+		Body:       rec.body,
+		StoredAt:   start,
+	}
+}
+
+// NewMemoryCacheStore returns an empty in-memory store holding at most
+// maxSize bytes of responses
+func NewMemoryCacheStore(maxSize int64) *MemoryCacheStore {
+	return &MemoryCacheStore{
+		maxSize: maxSize,
+		lru:     list.New(),
+		entries: map[string]*list.Element{},
+		tags:    map[string]map[string]struct{}{},
+		now:     time.Now,
+	}
+}
+
+// Get implements the CacheStore interface
+func (s *MemoryCacheStore) Get(ctx context.Context, key string) (*CachedResponse, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	elem, ok := s.entries[key]
+	if !ok {
+		return nil, nil
+	}
+	entry := elem.Value.(*memoryCacheEntry)
+	if !s.now().Before(entry.expires) {
+		s.remove(elem)
+		return nil, nil
+	}
+	s.lru.MoveToFront(elem)
+	return entry.resp, nil
+}
+
+// Set implements the CacheStore interface
+func (s *MemoryCacheStore) Set(ctx context.Context, key string, resp *CachedResponse, ttl time.Duration) error {
+	size := int64(len(key) + len(resp.Body))
+	for k, values := range resp.Header {
+		for _, v := range values {
+			size += int64(len(k) + len(v))
+		}
+	}
+	for _, tag := range resp.Tags {
+		size += int64(len(tag))
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if elem, ok := s.entries[key]; ok {
+		s.remove(elem)
+	}
+	if size > s.maxSize {
+		return nil
+	}
+	for s.size+size > s.maxSize {
+		s.remove(s.lru.Back())
+	}
+
+	entry := &memoryCacheEntry{key: key, resp: resp, size: size, expires: s.now().Add(ttl)}
+	s.entries[key] = s.lru.PushFront(entry)
+	s.size += size
+	for _, tag := range resp.Tags {
+		keys, ok := s.tags[tag]
+		if !ok {
+			keys = map[string]struct{}{}
+			s.tags[tag] = keys
+		}
+		keys[key] = struct{}{}
+	}
+	return nil
+}
+
+// Invalidate implements the CacheStore interface
+func (s *MemoryCacheStore) Invalidate(ctx context.Context, tags ...string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, tag := range tags {
+		for key := range s.tags[tag] {
+			if elem, ok := s.entries[key]; ok {
+				s.remove(elem)
+			}
+		}
+		delete(s.tags, tag)
+	}
+	return nil
+}
+
+// Size returns the size of the cached responses in bytes
+func (s *MemoryCacheStore) Size() int64 {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.size
+}
+
+func (s *MemoryCacheStore) remove(elem *list.Element) {
+	entry := s.lru.Remove(elem).(*memoryCacheEntry)
+	delete(s.entries, entry.key)
+	s.size -= entry.size
+	for _, tag := range entry.resp.Tags {
+		if keys, ok := s.tags[tag]; ok {
+			delete(keys, entry.key)
+			if len(keys) == 0 {
+				delete(s.tags, tag)
+			}
+		}
+	}
+}
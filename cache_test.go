@@ -0,0 +1,231 @@
+// Copyright 2026 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package fn
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestResponseCache(t *testing.T) {
+	SetResponseEncoder(func(ctx context.Context, payload interface{}) interface{} {
+		return payload
+	})
+	SetErrorEncoder(func(ctx context.Context, err error) interface{} {
+		return err.Error()
+	})
+	now := time.Now()
+	store := NewMemoryCacheStore(1 << 20)
+	store.now = func() time.Time { return now }
+	SetCacheStore(store)
+	defer SetCacheStore(NewMemoryCacheStore(defaultCacheSize))
+
+	calls := 0
+	handler := NewGroup().Cache(&CacheOptions{
+		TTL:   time.Minute,
+		Query: []string{"id"},
+		Vary:  []string{"Accept-Language"},
+		Tags:  []string{"orders"},
+	}).Plugin(func(ctx context.Context, r *http.Request) (context.Context, error) {
+		if user := r.Header.Get("X-User"); user != "" {
+			ctx = WithPrincipal(ctx, user)
+		}
+		return ctx, nil
+	}).Wrap(func(ctx context.Context, form *Form) (*testResponse, error) {
+		calls++
+		if form.Get("id") == "bad" {
+			return nil, errors.New("bad id")
+		}
+		CacheTags(ctx, "order:"+form.Get("id"))
+		ResponseHeader(ctx).Set("X-Order", form.Get("id"))
+		return &testResponse{Message: fmt.Sprintf("%s %d", form.Get("id"), calls)}, nil
+	})
+	serve := func(method, target string, header map[string]string) *httptest.ResponseRecorder {
+		request := httptest.NewRequest(method, target, nil)
+		for k, v := range header {
+			request.Header.Set(k, v)
+		}
+		recorder := httptest.NewRecorder()
+		handler.ServeHTTP(recorder, request)
+		return recorder
+	}
+
+	recorder := serve(http.MethodGet, "/orders?id=1", nil)
+	require.Equal(t, http.StatusOK, recorder.Code)
+	require.JSONEq(t, `{"code":0,"message":"1 1"}`, recorder.Body.String())
+	require.Equal(t, "Accept-Language", recorder.Header().Get("Vary"))
+	require.Empty(t, recorder.Header().Get("Age"))
+
+	// Served from the cache, the unselected query parameters are ignored
+	recorder = serve(http.MethodGet, "/orders?id=1&trace=x", nil)
+	require.Equal(t, http.StatusOK, recorder.Code)
+	require.JSONEq(t, `{"code":0,"message":"1 1"}`, recorder.Body.String())
+	require.Equal(t, "0", recorder.Header().Get("Age"))
+	require.Equal(t, "1", recorder.Header().Get("X-Order"))
+	require.Equal(t, "application/json; charset=utf-8", recorder.Header().Get("Content-Type"))
+	require.Equal(t, "Accept-Language", recorder.Header().Get("Vary"))
+	recorder = serve(http.MethodHead, "/orders?id=1", nil)
+	require.Equal(t, http.StatusOK, recorder.Code)
+	require.Empty(t, recorder.Body.String())
+	require.Equal(t, 1, calls)
+
+	// Different query, Vary header and principal
+	require.JSONEq(t, `{"code":0,"message":"2 2"}`, serve(http.MethodGet, "/orders?id=2", nil).Body.String())
+	require.JSONEq(t, `{"code":0,"message":"1 3"}`, serve(http.MethodGet, "/orders?id=1", map[string]string{"Accept-Language": "fr"}).Body.String())
+	require.JSONEq(t, `{"code":0,"message":"1 4"}`, serve(http.MethodGet, "/orders?id=1", map[string]string{"X-User": "alice"}).Body.String())
+	require.JSONEq(t, `{"code":0,"message":"1 4"}`, serve(http.MethodGet, "/orders?id=1", map[string]string{"X-User": "alice"}).Body.String())
+	require.Equal(t, 4, calls)
+
+	// no-cache refreshes the response, and no-store bypasses the cache
+	require.JSONEq(t, `{"code":0,"message":"1 5"}`, serve(http.MethodGet, "/orders?id=1", map[string]string{"Cache-Control": "no-cache"}).Body.String())
+	require.JSONEq(t, `{"code":0,"message":"1 5"}`, serve(http.MethodGet, "/orders?id=1", nil).Body.String())
+	require.JSONEq(t, `{"code":0,"message":"1 6"}`, serve(http.MethodGet, "/orders?id=1", map[string]string{"Cache-Control": "no-store"}).Body.String())
+	require.JSONEq(t, `{"code":0,"message":"1 5"}`, serve(http.MethodGet, "/orders?id=1", nil).Body.String())
+
+	// The errors and the unsafe methods are not cached
+	require.Equal(t, http.StatusBadRequest, serve(http.MethodGet, "/orders?id=bad", nil).Code)
+	require.Equal(t, http.StatusBadRequest, serve(http.MethodGet, "/orders?id=bad", nil).Code)
+	require.JSONEq(t, `{"code":0,"message":"1 9"}`, serve(http.MethodPost, "/orders?id=1", nil).Body.String())
+	require.JSONEq(t, `{"code":0,"message":"1 5"}`, serve(http.MethodGet, "/orders?id=1", nil).Body.String())
+	require.Equal(t, 9, calls)
+
+	// Invalidated by the tags
+	require.NoError(t, InvalidateCache(context.Background(), "order:2"))
+	require.JSONEq(t, `{"code":0,"message":"2 10"}`, serve(http.MethodGet, "/orders?id=2", nil).Body.String())
+	require.JSONEq(t, `{"code":0,"message":"1 5"}`, serve(http.MethodGet, "/orders?id=1", nil).Body.String())
+	require.NoError(t, InvalidateCache(context.Background(), "orders"))
+	require.JSONEq(t, `{"code":0,"message":"1 11"}`, serve(http.MethodGet, "/orders?id=1", nil).Body.String())
+
+	// Expired
+	now = now.Add(time.Minute)
+	require.JSONEq(t, `{"code":0,"message":"1 12"}`, serve(http.MethodGet, "/orders?id=1", nil).Body.String())
+	require.Equal(t, 12, calls)
+
+	// The credentials distinguish the responses without the principal
+	require.JSONEq(t, `{"code":0,"message":"3 13"}`, serve(http.MethodGet, "/orders?id=3", map[string]string{"Authorization": "Bearer alice"}).Body.String())
+	require.JSONEq(t, `{"code":0,"message":"3 14"}`, serve(http.MethodGet, "/orders?id=3", map[string]string{"Authorization": "Bearer bob"}).Body.String())
+	require.JSONEq(t, `{"code":0,"message":"3 15"}`, serve(http.MethodGet, "/orders?id=3", map[string]string{"Cookie": "session=bob"}).Body.String())
+	require.JSONEq(t, `{"code":0,"message":"3 13"}`, serve(http.MethodGet, "/orders?id=3", map[string]string{"Authorization": "Bearer alice"}).Body.String())
+	require.Equal(t, 15, calls)
+}
+
+func TestResponseCacheConditional(t *testing.T) {
+	SetResponseEncoder(func(ctx context.Context, payload interface{}) interface{} {
+		return payload
+	})
+	SetErrorEncoder(func(ctx context.Context, err error) interface{} {
+		return err.Error()
+	})
+	SetCacheStore(NewMemoryCacheStore(1 << 20))
+	defer SetCacheStore(NewMemoryCacheStore(defaultCacheSize))
+
+	calls := 0
+	handler := Wrap(func() (*testResponse, error) {
+		calls++
+		return &testResponse{Message: "hello"}, nil
+	}).ETag(&ETagOptions{}).Cache(&CacheOptions{}).Compression(&CompressionOptions{MinSize: 1})
+	serve := func(header map[string]string) *httptest.ResponseRecorder {
+		request := httptest.NewRequest(http.MethodGet, "/", nil)
+		for k, v := range header {
+			request.Header.Set(k, v)
+		}
+		recorder := httptest.NewRecorder()
+		handler.ServeHTTP(recorder, request)
+		return recorder
+	}
+
+	recorder := serve(map[string]string{"Accept-Encoding": "gzip"})
+	require.Equal(t, "gzip", recorder.Header().Get("Content-Encoding"))
+	etag := recorder.Header().Get("ETag")
+	require.Regexp(t, `^W/"[0-9a-f]{32}"$`, etag)
+
+	// The uncompressed response is cached
+	recorder = serve(nil)
+	require.Empty(t, recorder.Header().Get("Content-Encoding"))
+	require.Equal(t, etag[2:], recorder.Header().Get("ETag"))
+	require.JSONEq(t, `{"code":0,"message":"hello"}`, recorder.Body.String())
+
+	recorder = serve(map[string]string{"Accept-Encoding": "gzip", "If-None-Match": etag})
+	require.Equal(t, http.StatusNotModified, recorder.Code)
+	require.Empty(t, recorder.Body.String())
+
+	// The failed preconditions are the same as the ones of cache misses
+	recorder = serve(map[string]string{"If-Match": `"other"`})
+	require.Equal(t, http.StatusPreconditionFailed, recorder.Code)
+	require.Equal(t, `"precondition failed"`+"\n", recorder.Body.String())
+	require.Empty(t, recorder.Header().Get("Age"))
+	recorder = serve(map[string]string{"If-Match": etag[2:]})
+	require.Equal(t, http.StatusOK, recorder.Code)
+	require.Equal(t, 1, calls)
+
+	// The cached response without ETag matches "*"
+	plain := Wrap(func() (*testResponse, error) {
+		calls++
+		return &testResponse{Message: "plain"}, nil
+	}).Cache(&CacheOptions{})
+	for i := 0; i < 2; i++ {
+		request := httptest.NewRequest(http.MethodGet, "/plain", nil)
+		request.Header.Set("If-Match", "*")
+		recorder = httptest.NewRecorder()
+		plain.ServeHTTP(recorder, request)
+		require.Equal(t, http.StatusOK, recorder.Code)
+		require.JSONEq(t, `{"code":0,"message":"plain"}`, recorder.Body.String())
+	}
+	require.Equal(t, "0", recorder.Header().Get("Age"))
+	require.Equal(t, 2, calls)
+}
+
+func TestMemoryCacheStore(t *testing.T) {
+	ctx := context.Background()
+	store := NewMemoryCacheStore(100)
+	response := func(body string, tags ...string) *CachedResponse {
+		return &CachedResponse{StatusCode: http.StatusOK, Body: []byte(body), Tags: tags}
+	}
+	body := string(make([]byte, 39))
+
+	require.NoError(t, store.Set(ctx, "a", response(body, "x"), time.Minute))
+	require.NoError(t, store.Set(ctx, "b", response(body, "x", "y"), time.Minute))
+	require.Equal(t, int64(83), store.Size())
+
+	// a is used recently, so that b is evicted
+	resp, err := store.Get(ctx, "a")
+	require.NoError(t, err)
+	require.NotNil(t, resp)
+	require.NoError(t, store.Set(ctx, "c", response(body), time.Minute))
+	resp, _ = store.Get(ctx, "b")
+	require.Nil(t, resp)
+	require.Equal(t, int64(81), store.Size())
+	_, ok := store.tags["y"]
+	require.False(t, ok)
+
+	// Larger than the store
+	require.NoError(t, store.Set(ctx, "d", response(string(make([]byte, 100))), time.Minute))
+	resp, _ = store.Get(ctx, "d")
+	require.Nil(t, resp)
+
+	require.NoError(t, store.Invalidate(ctx, "x"))
+	resp, _ = store.Get(ctx, "a")
+	require.Nil(t, resp)
+	resp, _ = store.Get(ctx, "c")
+	require.NotNil(t, resp)
+	require.Equal(t, int64(40), store.Size())
+}
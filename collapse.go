@@ -0,0 +1,207 @@
+// Copyright 2026 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package fn
+
+import (
+	"context"
+	"net/http"
+	"sync"
+	"time"
+)
+
+type (
+	// CollapseKeyFunc returns the key of the request, the concurrent
+	// requests with the same key share a single invocation, and the request
+	// is not collapsed if the key is empty
+	CollapseKeyFunc func(ctx context.Context, r *http.Request) string
+
+	// CollapseOptions configures collapsing the concurrent identical GET
+	// requests into a single invocation of the wrapped function
+	CollapseOptions struct {
+		// Query are the query parameters distinguishing the requests, all
+		// parameters if empty
+		Query []string
+		// Vary are the request headers distinguishing the requests
+		Vary []string
+		// Key overrides the key made of the path, Query, Vary and the
+		// principal
+		Key CollapseKeyFunc
+	}
+
+	// flight is an invocation shared by the concurrent requests
+	flight struct {
+		done     chan struct{}
+		waiters  int
+		cancel   context.CancelFunc
+		resp     *CachedResponse
+		panicked bool
+		p        interface{}
+	}
+
+	// flights are the invocations in flight
+	flights struct {
+		mu      sync.Mutex
+		flights map[string]*flight
+	}
+
+	// detachedContext carries the values of parent without its deadline
+	// and cancellation
+	detachedContext struct {
+		context.Context
+	}
+
+	// sharedWriter buffers the shared response
+	sharedWriter struct {
+		header http.Header
+	}
+)
+
+var inflight = &flights{flights: map[string]*flight{}}
+
+// conditionalHeaders are evaluated by every request against the shared
+// response
+var conditionalHeaders = []string{"If-Match", "If-None-Match", "If-Modified-Since", "If-Unmodified-Since"}
+
+func (o *CollapseOptions) key(ctx context.Context, r *http.Request) string {
+	if o.Key != nil {
+		return o.Key(ctx, r)
+	}
+	return cacheKey(ctx, &CacheOptions{Query: o.Query, Vary: o.Vary}, r)
+}
+
+// collapsed joins the invocation of key, the first request starts it with
+// the context detached from its cancellation, which is canceled once all
+// requests have left. Every request waits for the shared response or its
+// own cancellation, and the waiting is observed as its handler phase.
+func (fn *fn) collapsed(ctx context.Context, x *exchange, w http.ResponseWriter, r *http.Request, key string) {
+	f, shared := inflight.join(ctx, key)
+	if shared != nil {
+		// The headers are copied before the request may write its own
+		// response, e.g. when it leaves
+		header := cloneHeader(w.Header())
+		sr := r.WithContext(shared)
+		sr.Header = cloneHeader(r.Header)
+		go fn.share(shared, header, sr, key, f)
+	}
+
+	x.beginPhase(phaseHandler)
+	select {
+	case <-f.done:
+		x.endPhase(phaseHandler, nil)
+	case <-ctx.Done():
+		inflight.leave(key, f)
+		x.endPhase(phaseHandler, ctx.Err())
+		x.failure(ctx, w, ctx.Err())
+		return
+	}
+	if f.panicked {
+		panic(f.p)
+	}
+
+	x.beginPhase(phaseEncode)
+	err := writeRecorded(w, r, f.resp, nil)
+	x.endPhase(phaseEncode, nil)
+	if err != nil {
+		x.failure(ctx, w, err)
+	}
+}
+
+// share runs the decode, handler and encode phases for all requests, which
+// are not observed by any of them since they may leave at any time. The
+// header of response and the request are owned by the invocation, and the
+// conditional headers are left to every request.
+func (fn *fn) share(ctx context.Context, header http.Header, r *http.Request, key string, f *flight) {
+	defer f.cancel()
+	defer inflight.forget(key, f)
+	defer func() {
+		if p := recover(); p != nil {
+			f.panicked, f.p = true, p
+		}
+		close(f.done)
+	}()
+
+	sw := &sharedWriter{header: header}
+	rec := newCacheRecorder(sw)
+	ctx = context.WithValue(ctx, responseHeaderKey{}, sw.header)
+	r = r.WithContext(ctx)
+	for _, h := range conditionalHeaders {
+		r.Header.Del(h)
+	}
+	start := time.Now()
+	fn.execute(ctx, &exchange{name: fn.name, request: r, start: start}, rec, r)
+	f.resp = rec.recorded(start)
+}
+
+// join returns the invocation of key, and the context of invocation if the
+// caller is the first request which starts it
+func (fs *flights) join(ctx context.Context, key string) (*flight, context.Context) {
+	fs.mu.Lock()
+	defer fs.mu.Unlock()
+	if f, ok := fs.flights[key]; ok {
+		f.waiters++
+		return f, nil
+	}
+	f := &flight{done: make(chan struct{}), waiters: 1}
+	ctx, f.cancel = context.WithCancel(detachedContext{ctx})
+	fs.flights[key] = f
+	return f, ctx
+}
+
+// leave cancels the invocation once all requests have left, and the later
+// requests start a new one
+func (fs *flights) leave(key string, f *flight) {
+	fs.mu.Lock()
+	defer fs.mu.Unlock()
+	f.waiters--
+	if f.waiters > 0 {
+		return
+	}
+	if fs.flights[key] == f {
+		delete(fs.flights, key)
+	}
+	f.cancel()
+}
+
+// forget removes the completed invocation, so that the later requests start
+// a new one
+func (fs *flights) forget(key string, f *flight) {
+	fs.mu.Lock()
+	defer fs.mu.Unlock()
+	if fs.flights[key] == f {
+		delete(fs.flights, key)
+	}
+}
+
+func (detachedContext) Deadline() (time.Time, bool) {
+	return time.Time{}, false
+}
+
+func (detachedContext) Done() <-chan struct{} {
+	return nil
+}
+
+func (detachedContext) Err() error {
+	return nil
+}
+
+func (sw *sharedWriter) Header() http.Header {
+	return sw.header
+}
+
+func (sw *sharedWriter) WriteHeader(statusCode int) {}
+
+func (sw *sharedWriter) Write(p []byte) (int, error) {
+	return len(p), nil
+}
@@ -0,0 +1,199 @@
+// Copyright 2026 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package fn
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+// waitWaiters waits until the invocation of key has n waiters
+func waitWaiters(t *testing.T, key string, n int) {
+	require.Eventually(t, func() bool {
+		inflight.mu.Lock()
+		defer inflight.mu.Unlock()
+		f, ok := inflight.flights[key]
+		return ok && f.waiters == n
+	}, 5*time.Second, time.Millisecond)
+}
+
+func TestCollapse(t *testing.T) {
+	SetResponseEncoder(func(ctx context.Context, payload interface{}) interface{} {
+		return payload
+	})
+	SetErrorEncoder(func(ctx context.Context, err error) interface{} {
+		return err.Error()
+	})
+
+	var calls int32
+	release := make(chan struct{})
+	handler := NewGroup().Collapse(&CollapseOptions{
+		Key: func(ctx context.Context, r *http.Request) string {
+			return r.URL.Query().Get("id")
+		},
+	}).ETag(&ETagOptions{}).Wrap(func(ctx context.Context, form *Form) (*testResponse, error) {
+		n := atomic.AddInt32(&calls, 1)
+		<-release
+		ResponseHeader(ctx).Set("X-Call", fmt.Sprint(n))
+		return &testResponse{Message: form.Get("id")}, nil
+	})
+	serve := func(target string, header map[string]string, results chan<- *httptest.ResponseRecorder) {
+		request := httptest.NewRequest(http.MethodGet, target, nil)
+		for k, v := range header {
+			request.Header.Set(k, v)
+		}
+		recorder := httptest.NewRecorder()
+		handler.ServeHTTP(recorder, request)
+		results <- recorder
+	}
+
+	// Learns the ETag of the response
+	close(release)
+	results := make(chan *httptest.ResponseRecorder, 4)
+	serve("/?id=1", nil, results)
+	etag := (<-results).Header().Get("ETag")
+	require.NotEmpty(t, etag)
+	release = make(chan struct{})
+
+	for i := 0; i < 3; i++ {
+		go serve("/?id=1", nil, results)
+	}
+	waitWaiters(t, "1", 3)
+	go serve("/?id=1", map[string]string{"If-None-Match": etag}, results)
+	waitWaiters(t, "1", 4)
+	others := make(chan *httptest.ResponseRecorder, 1)
+	go serve("/?id=2", nil, others)
+	waitWaiters(t, "2", 1)
+	close(release)
+
+	statuses := map[int]int{}
+	for i := 0; i < 4; i++ {
+		recorder := <-results
+		statuses[recorder.Code]++
+		require.Equal(t, "2", recorder.Header().Get("X-Call"))
+		require.Equal(t, etag, recorder.Header().Get("ETag"))
+		if recorder.Code == http.StatusOK {
+			require.JSONEq(t, `{"code":0,"message":"1"}`, recorder.Body.String())
+		}
+	}
+	require.Equal(t, map[int]int{http.StatusOK: 3, http.StatusNotModified: 1}, statuses)
+	require.JSONEq(t, `{"code":0,"message":"2"}`, (<-others).Body.String())
+	require.Equal(t, int32(3), atomic.LoadInt32(&calls))
+
+	// Started again after completed
+	inflight.mu.Lock()
+	require.Empty(t, inflight.flights)
+	inflight.mu.Unlock()
+	serve("/?id=1", nil, results)
+	require.Equal(t, "4", (<-results).Header().Get("X-Call"))
+}
+
+func TestCollapseCancel(t *testing.T) {
+	SetResponseEncoder(func(ctx context.Context, payload interface{}) interface{} {
+		return payload
+	})
+	SetErrorEncoder(func(ctx context.Context, err error) interface{} {
+		return err.Error()
+	})
+
+	release := make(chan struct{})
+	canceled := make(chan struct{})
+	handler := Wrap(func(ctx context.Context) (*testResponse, error) {
+		select {
+		case <-release:
+			return &testResponse{Message: "shared"}, nil
+		case <-ctx.Done():
+			close(canceled)
+			return nil, ctx.Err()
+		}
+	}).Collapse(&CollapseOptions{})
+	serve := func(ctx context.Context, results chan<- *httptest.ResponseRecorder) {
+		request := httptest.NewRequest(http.MethodGet, "/collapse", nil).WithContext(ctx)
+		recorder := httptest.NewRecorder()
+		handler.ServeHTTP(recorder, request)
+		results <- recorder
+	}
+	key := (&CollapseOptions{}).key(context.Background(), httptest.NewRequest(http.MethodGet, "/collapse", nil))
+
+	// The first request leaves, and the others still get the response
+	ctx1, cancel1 := context.WithCancel(context.Background())
+	first := make(chan *httptest.ResponseRecorder, 1)
+	go serve(ctx1, first)
+	waitWaiters(t, key, 1)
+	second := make(chan *httptest.ResponseRecorder, 1)
+	go serve(context.Background(), second)
+	waitWaiters(t, key, 2)
+	cancel1()
+	require.Equal(t, `"context canceled"`+"\n", (<-first).Body.String())
+	waitWaiters(t, key, 1)
+	close(release)
+	recorder := <-second
+	require.Equal(t, http.StatusOK, recorder.Code)
+	require.JSONEq(t, `{"code":0,"message":"shared"}`, recorder.Body.String())
+
+	// The invocation is canceled once all requests have left
+	release = make(chan struct{})
+	ctx, cancel := context.WithCancel(context.Background())
+	go serve(ctx, first)
+	waitWaiters(t, key, 1)
+	inflight.mu.Lock()
+	f := inflight.flights[key]
+	inflight.mu.Unlock()
+	cancel()
+	<-first
+	select {
+	case <-f.done:
+	case <-time.After(5 * time.Second):
+		t.Fatal("the invocation is not canceled")
+	}
+	<-canceled
+}
+
+func TestCollapseCancelCompressed(t *testing.T) {
+	// The shared invocations may outlive the requests, wait for them
+	var shared sync.WaitGroup
+	SetResponseEncoder(func(ctx context.Context, payload interface{}) interface{} {
+		defer shared.Done()
+		return payload
+	})
+	SetErrorEncoder(func(ctx context.Context, err error) interface{} {
+		return err.Error()
+	})
+
+	// The canceled request writes its response while the shared invocation
+	// starts with its header
+	handler := Wrap(func() (*testResponse, error) {
+		return &testResponse{Message: "shared"}, nil
+	}).Collapse(&CollapseOptions{}).Compression(&CompressionOptions{MinSize: 1})
+	for i := 0; i < 20; i++ {
+		ctx, cancel := context.WithCancel(context.Background())
+		cancel()
+		request := httptest.NewRequest(http.MethodGet, "/collapse", nil).WithContext(ctx)
+		request.Header.Set("Accept-Encoding", "gzip")
+		recorder := httptest.NewRecorder()
+		shared.Add(1)
+		handler.ServeHTTP(recorder, request)
+		require.Equal(t, "gzip", recorder.Header().Get("Content-Encoding"))
+	}
+	shared.Wait()
+}
@@ -0,0 +1,282 @@
+// Copyright 2026 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package fn
+
+import (
+	"compress/gzip"
+	"compress/zlib"
+	"errors"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+const (
+	defaultCompressionMinSize  = 1024
+	defaultMaxDecompressedSize = 32 << 20
+	encodingGzip               = "gzip"
+	encodingDeflate            = "deflate"
+)
+
+// ErrUnsupportedEncoding is returned (with status code 415) when the request
+// body is encoded by an unsupported content encoding
+var ErrUnsupportedEncoding = errors.New("unsupported content encoding")
+
+type (
+	// CompressionOptions configures the compression of responses and the
+	// decompression of requests
+	CompressionOptions struct {
+		// MinSize is the minimum size of responses to compress, 1024 bytes
+		// if zero
+		MinSize int
+		// Level is the compression level, gzip.DefaultCompression if zero
+		Level int
+		// MaxDecompressedSize limits the size of decompressed request body,
+		// which is the body limit (see SetRequestBodyLimit) if zero, or 32MB
+		// if the body is unlimited
+		MaxDecompressedSize int64
+	}
+
+	// compressWriter buffers the response until it reaches the minimum
+	// size, and then compresses it
+	compressWriter struct {
+		http.ResponseWriter
+		opts     *CompressionOptions
+		encoding string
+		status   int
+		buf      []byte
+		started  bool
+		zw       io.WriteCloser
+	}
+
+	// decompressedBody limits the size of decompressed request body
+	decompressedBody struct {
+		io.Reader
+		compressed io.Closer
+		limit      int64
+		read       int64
+	}
+)
+
+var globalCompression *CompressionOptions
+
+// SetCompression enables compressing the responses of all wrapped handlers
+// with gzip or deflate negotiated by the Accept-Encoding header, and
+// decoding the request bodies with Content-Encoding gzip or deflate, which
+// also applies to the batch and JSON-RPC endpoints. nil disables it.
+func SetCompression(opts *CompressionOptions) {
+	globalCompression = opts
+}
+
+// decompressRequest replaces the body encoded by Content-Encoding with the
+// decoded one
+func decompressRequest(r *http.Request, opts *CompressionOptions, bodyLimit int64) error {
+	encoding := strings.ToLower(strings.TrimSpace(r.Header.Get("Content-Encoding")))
+	if encoding == "" || encoding == "identity" || r.Body == nil || r.Body == http.NoBody {
+		return nil
+	}
+
+	var (
+		reader io.Reader
+		err    error
+	)
+	switch encoding {
+	case encodingGzip, "x-gzip":
+		reader, err = gzip.NewReader(r.Body)
+	case encodingDeflate:
+		reader, err = zlib.NewReader(r.Body)
+	default:
+		return ErrorWithStatusCode(ErrUnsupportedEncoding, http.StatusUnsupportedMediaType)
+	}
+	if err != nil {
+		if _, ok := UnwrapErrorStatusCode(err); ok {
+			return err
+		}
+		return ErrorWithStatusCode(err, http.StatusBadRequest)
+	}
+
+	limit := opts.MaxDecompressedSize
+	if limit <= 0 {
+		limit = bodyLimit
+	}
+	if limit <= 0 {
+		limit = defaultMaxDecompressedSize
+	}
+	r.Body = &decompressedBody{Reader: reader, compressed: r.Body, limit: limit}
+	r.Header.Del("Content-Encoding")
+	r.Header.Del("Content-Length")
+	r.ContentLength = -1
+	return nil
+}
+
+func (b *decompressedBody) Read(p []byte) (int, error) {
+	if b.read >= b.limit {
+		// Probe whether there is more data than the limit
+		var one [1]byte
+		n, err := b.Reader.Read(one[:])
+		if n > 0 {
+			return 0, ErrorWithStatusCode(ErrRequestBodyTooLarge, http.StatusRequestEntityTooLarge)
+		}
+		return 0, err
+	}
+	if remaining := b.limit - b.read; int64(len(p)) > remaining {
+		p = p[:remaining]
+	}
+	n, err := b.Reader.Read(p)
+	b.read += int64(n)
+	return n, err
+}
+
+func (b *decompressedBody) Close() error {
+	if c, ok := b.Reader.(io.Closer); ok {
+		_ = c.Close()
+	}
+	return b.compressed.Close()
+}
+
+// newCompressWriter wraps the writer if the client accepts a supported
+// encoding, the Vary header is always set since the response depends on the
+// Accept-Encoding header
+func newCompressWriter(w http.ResponseWriter, r *http.Request, opts *CompressionOptions) http.ResponseWriter {
+	addVary(w.Header(), "Accept-Encoding")
+	if r.Method == http.MethodHead {
+		return w
+	}
+	encoding := negotiateEncoding(r.Header.Get("Accept-Encoding"))
+	if encoding == "" {
+		return w
+	}
+	return &compressWriter{ResponseWriter: w, opts: opts, encoding: encoding}
+}
+
+func (cw *compressWriter) WriteHeader(statusCode int) {
+	if cw.status == 0 {
+		cw.status = statusCode
+	}
+}
+
+func (cw *compressWriter) Write(p []byte) (int, error) {
+	if cw.status == 0 {
+		cw.status = http.StatusOK
+	}
+	if cw.started {
+		if cw.zw != nil {
+			return cw.zw.Write(p)
+		}
+		return cw.ResponseWriter.Write(p)
+	}
+
+	cw.buf = append(cw.buf, p...)
+	minSize := cw.opts.MinSize
+	if minSize <= 0 {
+		minSize = defaultCompressionMinSize
+	}
+	if len(cw.buf) >= minSize {
+		if err := cw.start(true); err != nil {
+			return 0, err
+		}
+	}
+	return len(p), nil
+}
+
+// start writes the header and the buffered data, compressed or not
+func (cw *compressWriter) start(compress bool) error {
+	cw.started = true
+	header := cw.ResponseWriter.Header()
+	if header.Get("Content-Encoding") != "" || cw.status == http.StatusNoContent || cw.status == http.StatusNotModified {
+		compress = false
+	}
+	if compress {
+		level := cw.opts.Level
+		if level == 0 {
+			level = gzip.DefaultCompression
+		}
+		var err error
+		if cw.encoding == encodingGzip {
+			cw.zw, err = gzip.NewWriterLevel(cw.ResponseWriter, level)
+		} else {
+			cw.zw, err = zlib.NewWriterLevel(cw.ResponseWriter, level)
+		}
+		if err != nil {
+			return err
+		}
+		header.Set("Content-Encoding", cw.encoding)
+		header.Del("Content-Length")
+		// The compressed body is not byte-identical to the one validated
+		if etag := header.Get("ETag"); strings.HasPrefix(etag, `"`) {
+			header.Set("ETag", "W/"+etag)
+		}
+	}
+
+	if cw.status != 0 {
+		cw.ResponseWriter.WriteHeader(cw.status)
+	}
+	if len(cw.buf) == 0 {
+		return nil
+	}
+	var err error
+	if cw.zw != nil {
+		_, err = cw.zw.Write(cw.buf)
+	} else {
+		_, err = cw.ResponseWriter.Write(cw.buf)
+	}
+	cw.buf = nil
+	return err
+}
+
+// close flushes the response
+func (cw *compressWriter) close() {
+	if !cw.started {
+		_ = cw.start(false)
+	}
+	if cw.zw != nil {
+		_ = cw.zw.Close()
+	}
+}
+
+// negotiateEncoding returns the supported encoding with the highest quality
+// in the Accept-Encoding header, gzip is preferred over deflate
+func negotiateEncoding(accept string) string {
+	qualities := map[string]float64{}
+	for _, part := range strings.Split(accept, ",") {
+		fields := strings.Split(part, ";")
+		encoding := strings.ToLower(strings.TrimSpace(fields[0]))
+		q := 1.0
+		for _, param := range fields[1:] {
+			if param = strings.TrimSpace(param); strings.HasPrefix(param, "q=") {
+				v, err := strconv.ParseFloat(param[2:], 64)
+				if err != nil {
+					v = 0
+				}
+				q = v
+			}
+		}
+		qualities[encoding] = q
+	}
+
+	best, bestQ := "", 0.0
+	for _, encoding := range []string{encodingGzip, encodingDeflate} {
+		q, ok := qualities[encoding]
+		if !ok {
+			// The wildcard matches the encodings not listed
+			q = qualities["*"]
+		}
+		if q > bestQ {
+			best, bestQ = encoding, q
+		}
+	}
+	return best
+}
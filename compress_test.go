@@ -0,0 +1,160 @@
+// Copyright 2026 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package fn
+
+import (
+	"bytes"
+	"compress/gzip"
+	"compress/zlib"
+	"context"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func gzipBytes(t *testing.T, data string) []byte {
+	buf := &bytes.Buffer{}
+	zw := gzip.NewWriter(buf)
+	_, err := zw.Write([]byte(data))
+	require.NoError(t, err)
+	require.NoError(t, zw.Close())
+	return buf.Bytes()
+}
+
+func TestCompressResponse(t *testing.T) {
+	SetResponseEncoder(func(ctx context.Context, payload interface{}) interface{} {
+		return payload
+	})
+	SetErrorEncoder(func(ctx context.Context, err error) interface{} {
+		return err.Error()
+	})
+
+	handler := NewGroup().Compression(&CompressionOptions{MinSize: 64}).Wrap(func(req *testRequest) (*testResponse, error) {
+		if req.Bar < 0 {
+			return nil, nil
+		}
+		return &testResponse{Message: strings.Repeat(req.Foo, req.Bar)}, nil
+	})
+
+	serve := func(acceptEncoding, body string) *httptest.ResponseRecorder {
+		request := httptest.NewRequest(http.MethodPost, "/", strings.NewReader(body))
+		request.Header.Set("Accept-Encoding", acceptEncoding)
+		recorder := httptest.NewRecorder()
+		handler.ServeHTTP(recorder, request)
+		return recorder
+	}
+	large := `{"code":0,"message":"` + strings.Repeat("a", 100) + `"}` + "\n"
+
+	recorder := serve("gzip, deflate", `{"foo":"a","bar":100}`)
+	require.Equal(t, http.StatusOK, recorder.Code)
+	require.Equal(t, "gzip", recorder.Header().Get("Content-Encoding"))
+	require.Equal(t, "Accept-Encoding", recorder.Header().Get("Vary"))
+	require.Equal(t, "application/json; charset=utf-8", recorder.Header().Get("Content-Type"))
+	zr, err := gzip.NewReader(recorder.Body)
+	require.NoError(t, err)
+	data, err := ioutil.ReadAll(zr)
+	require.NoError(t, err)
+	require.Equal(t, large, string(data))
+
+	recorder = serve("gzip;q=0.5, deflate", `{"foo":"a","bar":100}`)
+	require.Equal(t, "deflate", recorder.Header().Get("Content-Encoding"))
+	zr2, err := zlib.NewReader(recorder.Body)
+	require.NoError(t, err)
+	data, err = ioutil.ReadAll(zr2)
+	require.NoError(t, err)
+	require.Equal(t, large, string(data))
+
+	// Smaller than the minimum size
+	recorder = serve("gzip", `{"foo":"a","bar":1}`)
+	require.Empty(t, recorder.Header().Get("Content-Encoding"))
+	require.Equal(t, "Accept-Encoding", recorder.Header().Get("Vary"))
+	require.JSONEq(t, `{"code":0,"message":"a"}`, recorder.Body.String())
+
+	// Not accepted
+	recorder = serve("gzip;q=0, identity", `{"foo":"a","bar":100}`)
+	require.Empty(t, recorder.Header().Get("Content-Encoding"))
+	require.Equal(t, large, recorder.Body.String())
+
+	// Empty and error responses
+	recorder = serve("gzip", `{"bar":-1}`)
+	require.Equal(t, http.StatusNoContent, recorder.Code)
+	require.Empty(t, recorder.Header().Get("Content-Encoding"))
+	recorder = serve("gzip", `{`)
+	require.Equal(t, http.StatusBadRequest, recorder.Code)
+	require.Empty(t, recorder.Header().Get("Content-Encoding"))
+}
+
+func TestDecompressRequest(t *testing.T) {
+	SetErrorEncoder(func(ctx context.Context, err error) interface{} {
+		return err.Error()
+	})
+	SetCompression(&CompressionOptions{MaxDecompressedSize: 64})
+	defer SetCompression(nil)
+
+	handler := Wrap(func(req *testRequest) (*testResponse, error) {
+		return &testResponse{Message: req.Foo}, nil
+	})
+	serve := func(encoding string, body []byte) *httptest.ResponseRecorder {
+		request := httptest.NewRequest(http.MethodPost, "/", bytes.NewReader(body))
+		request.Header.Set("Content-Encoding", encoding)
+		recorder := httptest.NewRecorder()
+		handler.ServeHTTP(recorder, request)
+		return recorder
+	}
+
+	recorder := serve("gzip", gzipBytes(t, `{"foo":"compressed"}`))
+	require.Equal(t, http.StatusOK, recorder.Code)
+	require.JSONEq(t, `{"code":0,"message":"compressed"}`, recorder.Body.String())
+
+	deflated := &bytes.Buffer{}
+	zw := zlib.NewWriter(deflated)
+	_, _ = io.WriteString(zw, `{"foo":"deflated"}`)
+	require.NoError(t, zw.Close())
+	recorder = serve("deflate", deflated.Bytes())
+	require.JSONEq(t, `{"code":0,"message":"deflated"}`, recorder.Body.String())
+
+	// The zip bomb is rejected by the decompressed size
+	bomb := gzipBytes(t, `{"foo":"`+strings.Repeat("a", 1<<20)+`"}`)
+	require.True(t, len(bomb) < 64*1024)
+	recorder = serve("gzip", bomb)
+	require.Equal(t, http.StatusRequestEntityTooLarge, recorder.Code)
+	require.Equal(t, `"request body too large"`+"\n", recorder.Body.String())
+
+	require.Equal(t, http.StatusBadRequest, serve("gzip", []byte(`{"foo":"plain"}`)).Code)
+	require.Equal(t, http.StatusUnsupportedMediaType, serve("br", []byte(`{}`)).Code)
+}
+
+func TestNegotiateEncoding(t *testing.T) {
+	for accept, expected := range map[string]string{
+		"":                        "",
+		"gzip":                    "gzip",
+		"deflate":                 "deflate",
+		"deflate, gzip":           "gzip",
+		"gzip;q=0.5, deflate":     "deflate",
+		"GZIP":                    "gzip",
+		"br, identity":            "",
+		"*":                       "gzip",
+		"*;q=0.5, gzip;q=0":       "deflate",
+		"gzip;q=0, deflate;q=0":   "",
+		"gzip;q=abc, deflate;q=1": "deflate",
+	} {
+		require.Equal(t, expected, negotiateEncoding(accept), accept)
+	}
+}
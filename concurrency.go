@@ -0,0 +1,217 @@
+// Copyright 2026 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package fn
+
+import (
+	"container/list"
+	"context"
+	"errors"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// ErrConcurrencyLimited is returned (with status code 503) when the request
+// is shed by the concurrency limiter
+var ErrConcurrencyLimited = errors.New("too many concurrent requests")
+
+const (
+	defaultConcurrencyRetryAfter = time.Second
+	aimdBackoff                  = 0.9
+)
+
+// ConcurrencyLimiter limits the number of requests served concurrently, the
+// excess requests wait in a bounded queue, and are rejected with 503 and the
+// Retry-After header if the queue is full or the waiting times out.
+type ConcurrencyLimiter struct {
+	mu           sync.Mutex
+	limit        int
+	inFlight     int
+	queue        *list.List // of chan struct{}
+	maxQueue     int
+	queueTimeout time.Duration
+	retryAfter   time.Duration
+
+	// The adaptive limit, which increases by one after a limit of requests
+	// served within the target latency, and decreases multiplicatively once
+	// a request exceeds the target latency
+	adaptive   bool
+	minLimit   int
+	maxLimit   int
+	target     time.Duration
+	successive int
+}
+
+// NewConcurrencyLimiter returns a limiter serving up to limit requests
+// concurrently without queueing.
+//
+//	limiter := fn.NewConcurrencyLimiter(16).Queue(64, time.Second)
+//	http.Handle("/report", fn.Wrap(report).Concurrency(limiter))
+func NewConcurrencyLimiter(limit int) *ConcurrencyLimiter {
+	if limit < 1 {
+		panic("concurrency limit should be positive")
+	}
+	return &ConcurrencyLimiter{
+		limit:      limit,
+		queue:      list.New(),
+		retryAfter: defaultConcurrencyRetryAfter,
+	}
+}
+
+// Queue lets up to size requests wait for at most timeout when the limit is
+// reached, zero timeout means waiting until the request is canceled
+func (l *ConcurrencyLimiter) Queue(size int, timeout time.Duration) *ConcurrencyLimiter {
+	l.maxQueue = size
+	l.queueTimeout = timeout
+	return l
+}
+
+// RetryAfter sets the Retry-After header of the rejected requests, which is
+// one second by default
+func (l *ConcurrencyLimiter) RetryAfter(d time.Duration) *ConcurrencyLimiter {
+	l.retryAfter = d
+	return l
+}
+
+// Adaptive adjusts the limit between min and max with AIMD (additive
+// increase, multiplicative decrease) according to whether the latency of
+// served requests exceeds the target
+func (l *ConcurrencyLimiter) Adaptive(min, max int, target time.Duration) *ConcurrencyLimiter {
+	if min < 1 || max < min {
+		panic("adaptive concurrency limit should satisfy 1 <= min <= max")
+	}
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.adaptive = true
+	l.minLimit, l.maxLimit, l.target = min, max, target
+	if l.limit < min {
+		l.limit = min
+	}
+	if l.limit > max {
+		l.limit = max
+	}
+	return l
+}
+
+// Limit returns the current limit
+func (l *ConcurrencyLimiter) Limit() int {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.limit
+}
+
+// InFlight returns the number of requests being served
+func (l *ConcurrencyLimiter) InFlight() int {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.inFlight
+}
+
+// Queued returns the number of requests waiting in the queue
+func (l *ConcurrencyLimiter) Queued() int {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.queue.Len()
+}
+
+// acquire waits for a slot, the Retry-After header is set if the request is
+// rejected
+func (l *ConcurrencyLimiter) acquire(ctx context.Context, header http.Header) error {
+	l.mu.Lock()
+	if l.inFlight < l.limit && l.queue.Len() == 0 {
+		l.inFlight++
+		l.mu.Unlock()
+		return nil
+	}
+	if l.queue.Len() >= l.maxQueue {
+		l.mu.Unlock()
+		return l.reject(header)
+	}
+	ready := make(chan struct{})
+	elem := l.queue.PushBack(ready)
+	l.mu.Unlock()
+
+	var expired <-chan time.Time
+	if l.queueTimeout > 0 {
+		timer := time.NewTimer(l.queueTimeout)
+		defer timer.Stop()
+		expired = timer.C
+	}
+
+	var err error
+	select {
+	case <-ready:
+		return nil
+	case <-expired:
+		err = l.reject(header)
+	case <-ctx.Done():
+		err = ErrorWithStatusCode(ctx.Err(), http.StatusServiceUnavailable)
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	select {
+	case <-ready:
+		// The slot is granted while giving up, pass it to the next one
+		l.inFlight--
+		l.dispatch()
+	default:
+		l.queue.Remove(elem)
+	}
+	return err
+}
+
+// release releases the slot and adjusts the limit with the latency
+func (l *ConcurrencyLimiter) release(latency time.Duration) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.inFlight--
+	if l.adaptive {
+		if latency > l.target {
+			limit := int(float64(l.limit) * aimdBackoff)
+			if limit == l.limit {
+				limit--
+			}
+			if limit < l.minLimit {
+				limit = l.minLimit
+			}
+			l.limit = limit
+			l.successive = 0
+		} else if l.successive++; l.successive >= l.limit {
+			if l.limit < l.maxLimit {
+				l.limit++
+			}
+			l.successive = 0
+		}
+	}
+	l.dispatch()
+}
+
+// dispatch grants the slots to the waiting requests in order
+func (l *ConcurrencyLimiter) dispatch() {
+	for l.inFlight < l.limit && l.queue.Len() > 0 {
+		ready := l.queue.Remove(l.queue.Front()).(chan struct{})
+		l.inFlight++
+		close(ready)
+	}
+}
+
+func (l *ConcurrencyLimiter) reject(header http.Header) error {
+	if l.retryAfter > 0 {
+		header.Set("Retry-After", strconv.FormatInt(ceilSeconds(l.retryAfter), 10))
+	}
+	return ErrorWithStatusCode(ErrConcurrencyLimited, http.StatusServiceUnavailable)
+}
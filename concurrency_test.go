@@ -0,0 +1,123 @@
+// Copyright 2026 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package fn
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestConcurrencyLimiter(t *testing.T) {
+	SetErrorEncoder(func(ctx context.Context, err error) interface{} {
+		return err.Error()
+	})
+
+	limiter := NewConcurrencyLimiter(1).Queue(1, time.Minute).RetryAfter(3 * time.Second)
+	unblock := make(chan struct{})
+	group := NewGroup().Concurrency(limiter)
+	handler := group.Wrap(func() error {
+		<-unblock
+		return nil
+	})
+
+	serve := func() <-chan *httptest.ResponseRecorder {
+		done := make(chan *httptest.ResponseRecorder, 1)
+		go func() {
+			recorder := httptest.NewRecorder()
+			handler.ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, "/", nil))
+			done <- recorder
+		}()
+		return done
+	}
+
+	first := serve()
+	require.Eventually(t, func() bool { return limiter.InFlight() == 1 }, time.Second, time.Millisecond)
+	second := serve()
+	require.Eventually(t, func() bool { return limiter.Queued() == 1 }, time.Second, time.Millisecond)
+
+	// The queue is full
+	recorder := <-serve()
+	require.Equal(t, http.StatusServiceUnavailable, recorder.Code)
+	require.Equal(t, "3", recorder.Header().Get("Retry-After"))
+	require.Equal(t, `"too many concurrent requests"`+"\n", recorder.Body.String())
+
+	// The queued request is served after the first one
+	unblock <- struct{}{}
+	require.Equal(t, http.StatusNoContent, (<-first).Code)
+	require.Eventually(t, func() bool { return limiter.Queued() == 0 }, time.Second, time.Millisecond)
+	require.Equal(t, 1, limiter.InFlight())
+	unblock <- struct{}{}
+	require.Equal(t, http.StatusNoContent, (<-second).Code)
+	require.Equal(t, 0, limiter.InFlight())
+}
+
+func TestConcurrencyLimiterQueueTimeout(t *testing.T) {
+	limiter := NewConcurrencyLimiter(1).Queue(1, 20*time.Millisecond)
+	require.NoError(t, limiter.acquire(context.Background(), http.Header{}))
+
+	// Waiting times out
+	header := http.Header{}
+	err := limiter.acquire(context.Background(), header)
+	code, _ := UnwrapErrorStatusCode(err)
+	require.Equal(t, http.StatusServiceUnavailable, code)
+	require.Equal(t, "1", header.Get("Retry-After"))
+	require.Equal(t, 0, limiter.Queued())
+
+	// Waiting is canceled
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error, 1)
+	go func() { done <- limiter.Queue(1, 0).acquire(ctx, http.Header{}) }()
+	require.Eventually(t, func() bool { return limiter.Queued() == 1 }, time.Second, time.Millisecond)
+	cancel()
+	require.Error(t, <-done)
+	require.Equal(t, 0, limiter.Queued())
+
+	limiter.release(0)
+	require.Equal(t, 0, limiter.InFlight())
+}
+
+func TestAdaptiveConcurrencyLimiter(t *testing.T) {
+	limiter := NewConcurrencyLimiter(4).Adaptive(2, 5, 10*time.Millisecond)
+	serve := func(latency time.Duration) {
+		require.NoError(t, limiter.acquire(context.Background(), http.Header{}))
+		limiter.release(latency)
+	}
+
+	// Increased by one after a limit of fast requests
+	for i := 0; i < 3; i++ {
+		serve(time.Millisecond)
+	}
+	require.Equal(t, 4, limiter.Limit())
+	serve(time.Millisecond)
+	require.Equal(t, 5, limiter.Limit())
+	for i := 0; i < 10; i++ {
+		serve(time.Millisecond)
+	}
+	require.Equal(t, 5, limiter.Limit())
+
+	// Decreased once a request is slow
+	serve(time.Second)
+	require.Equal(t, 4, limiter.Limit())
+	serve(time.Second)
+	require.Equal(t, 3, limiter.Limit())
+	serve(time.Second)
+	serve(time.Second)
+	require.Equal(t, 2, limiter.Limit())
+}
@@ -0,0 +1,217 @@
+// Copyright 2026 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package fn
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+var defaultCORSMethods = []string{http.MethodGet, http.MethodHead, http.MethodPost}
+
+// CORSOptions configures the cross-origin resource sharing
+type CORSOptions struct {
+	// AllowedOrigins are the origins allowed to access, "*" allows all
+	// origins, and an origin may contain a wildcard, e.g.
+	// https://*.example.com
+	AllowedOrigins []string
+	// AllowOriginFunc is consulted if the origin is not in AllowedOrigins
+	AllowOriginFunc func(origin string) bool
+	// AllowedMethods are the methods allowed in preflight, GET, HEAD and
+	// POST if empty
+	AllowedMethods []string
+	// AllowedHeaders are the request headers allowed in preflight, "*"
+	// allows all headers, the requested headers are allowed if empty
+	AllowedHeaders []string
+	// ExposedHeaders are the response headers readable by the clients
+	ExposedHeaders []string
+	// AllowCredentials allows the requests with credentials, e.g. cookies
+	AllowCredentials bool
+	// MaxAge is how long the result of preflight can be cached
+	MaxAge time.Duration
+}
+
+// cors is the compiled CORSOptions
+type cors struct {
+	opts           CORSOptions
+	allowAll       bool
+	origins        map[string]bool
+	patterns       [][2]string // prefix and suffix around the wildcard
+	methods        map[string]bool
+	allowedMethods string
+	headers        map[string]bool
+	allowAllHeader bool
+	exposedHeaders string
+}
+
+var globalCORS *cors
+
+// SetCORS enables CORS for all wrapped handlers, JSON-RPC and batch, nil
+// disables it. The preflight requests are answered before the plugins, and
+// the CORS headers are set on both the successful and the error responses.
+func SetCORS(opts *CORSOptions) {
+	globalCORS = newCORS(opts)
+}
+
+func newCORS(opts *CORSOptions) *cors {
+	if opts == nil {
+		return nil
+	}
+	c := &cors{
+		opts:    *opts,
+		origins: map[string]bool{},
+		methods: map[string]bool{},
+		headers: map[string]bool{},
+	}
+	for _, origin := range opts.AllowedOrigins {
+		origin = strings.ToLower(origin)
+		switch i := strings.IndexByte(origin, '*'); {
+		case origin == "*":
+			c.allowAll = true
+		case i >= 0:
+			c.patterns = append(c.patterns, [2]string{origin[:i], origin[i+1:]})
+		default:
+			c.origins[origin] = true
+		}
+	}
+
+	methods := opts.AllowedMethods
+	if len(methods) == 0 {
+		methods = defaultCORSMethods
+	}
+	for i := range methods {
+		c.methods[strings.ToUpper(methods[i])] = true
+	}
+	c.allowedMethods = strings.ToUpper(strings.Join(methods, ", "))
+
+	for _, header := range opts.AllowedHeaders {
+		if header == "*" {
+			c.allowAllHeader = true
+		}
+		c.headers[http.CanonicalHeaderKey(header)] = true
+	}
+	c.exposedHeaders = strings.Join(opts.ExposedHeaders, ", ")
+	return c
+}
+
+// handle sets the CORS headers, it answers the preflight request and
+// returns true if the request is a preflight
+func (c *cors) handle(w http.ResponseWriter, r *http.Request) bool {
+	origin := r.Header.Get("Origin")
+	preflight := r.Method == http.MethodOptions && r.Header.Get("Access-Control-Request-Method") != ""
+	header := w.Header()
+	if preflight {
+		addVary(header, "Origin", "Access-Control-Request-Method", "Access-Control-Request-Headers")
+		if origin != "" && c.allowOrigin(origin) && c.preflight(header, r) {
+			c.setOrigin(header, origin)
+		}
+		w.WriteHeader(http.StatusNoContent)
+		return true
+	}
+
+	addVary(header, "Origin")
+	if origin == "" || !c.allowOrigin(origin) {
+		return false
+	}
+	c.setOrigin(header, origin)
+	if c.exposedHeaders != "" {
+		header.Set("Access-Control-Expose-Headers", c.exposedHeaders)
+	}
+	return false
+}
+
+// preflight sets the headers of preflight response if the requested method
+// and headers are allowed
+func (c *cors) preflight(header http.Header, r *http.Request) bool {
+	method := strings.ToUpper(r.Header.Get("Access-Control-Request-Method"))
+	if !c.methods[method] {
+		return false
+	}
+
+	requested := r.Header.Get("Access-Control-Request-Headers")
+	if requested != "" && len(c.headers) > 0 && !c.allowAllHeader {
+		for _, h := range strings.Split(requested, ",") {
+			if h = strings.TrimSpace(h); h != "" && !c.headers[http.CanonicalHeaderKey(h)] {
+				return false
+			}
+		}
+	}
+
+	header.Set("Access-Control-Allow-Methods", c.allowedMethods)
+	if requested != "" {
+		header.Set("Access-Control-Allow-Headers", requested)
+	}
+	if c.opts.MaxAge > 0 {
+		header.Set("Access-Control-Max-Age", strconv.Itoa(int(c.opts.MaxAge/time.Second)))
+	}
+	return true
+}
+
+func (c *cors) setOrigin(header http.Header, origin string) {
+	if c.allowAll && !c.opts.AllowCredentials {
+		header.Set("Access-Control-Allow-Origin", "*")
+	} else {
+		header.Set("Access-Control-Allow-Origin", origin)
+	}
+	if c.opts.AllowCredentials {
+		header.Set("Access-Control-Allow-Credentials", "true")
+	}
+}
+
+func (c *cors) allowOrigin(origin string) bool {
+	if c.allowAll {
+		return true
+	}
+	lower := strings.ToLower(origin)
+	if c.origins[lower] {
+		return true
+	}
+	for _, p := range c.patterns {
+		if len(lower) > len(p[0])+len(p[1]) && strings.HasPrefix(lower, p[0]) && strings.HasSuffix(lower, p[1]) {
+			return true
+		}
+	}
+	return c.opts.AllowOriginFunc != nil && c.opts.AllowOriginFunc(origin)
+}
+
+// handleCORS applies the CORS policy of handler, or the global one if the
+// handler has none
+func handleCORS(c *cors, w http.ResponseWriter, r *http.Request) bool {
+	if c == nil {
+		c = globalCORS
+	}
+	if c == nil {
+		return false
+	}
+	return c.handle(w, r)
+}
+
+// addVary adds the values to the Vary header without duplication
+func addVary(header http.Header, values ...string) {
+	existing := map[string]bool{}
+	for _, v := range header["Vary"] {
+		for _, field := range strings.Split(v, ",") {
+			existing[strings.ToLower(strings.TrimSpace(field))] = true
+		}
+	}
+	for _, v := range values {
+		if !existing[strings.ToLower(v)] {
+			header.Add("Vary", v)
+			existing[strings.ToLower(v)] = true
+		}
+	}
+}
@@ -0,0 +1,138 @@
+// Copyright 2026 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package fn
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestCORS(t *testing.T) {
+	var plugins int
+	group := NewGroup().CORS(&CORSOptions{
+		AllowedOrigins:   []string{"https://app.example.com", "https://*.example.org"},
+		AllowedMethods:   []string{"GET", "POST", "DELETE"},
+		AllowedHeaders:   []string{"Content-Type", "X-Token"},
+		ExposedHeaders:   []string{"X-Request-ID"},
+		AllowCredentials: true,
+		MaxAge:           10 * time.Minute,
+	}).Plugin(func(ctx context.Context, r *http.Request) (context.Context, error) {
+		plugins++
+		if r.Header.Get("X-Token") == "" {
+			return ctx, ErrorWithStatusCode(errors.New("unauthorized"), http.StatusUnauthorized)
+		}
+		return ctx, nil
+	})
+	handler := group.Wrap(func(req *testRequest) (*testResponse, error) {
+		return &testResponse{Message: req.Foo}, nil
+	})
+
+	preflight := func(origin, method, headers string) *httptest.ResponseRecorder {
+		request := httptest.NewRequest(http.MethodOptions, "/", nil)
+		request.Header.Set("Origin", origin)
+		request.Header.Set("Access-Control-Request-Method", method)
+		if headers != "" {
+			request.Header.Set("Access-Control-Request-Headers", headers)
+		}
+		recorder := httptest.NewRecorder()
+		handler.ServeHTTP(recorder, request)
+		return recorder
+	}
+
+	// Answered before the plugins and decoding
+	recorder := preflight("https://app.example.com", "DELETE", "content-type, x-token")
+	require.Equal(t, http.StatusNoContent, recorder.Code)
+	require.Equal(t, 0, plugins)
+	require.Empty(t, recorder.Body.String())
+	require.Equal(t, "https://app.example.com", recorder.Header().Get("Access-Control-Allow-Origin"))
+	require.Equal(t, "GET, POST, DELETE", recorder.Header().Get("Access-Control-Allow-Methods"))
+	require.Equal(t, "content-type, x-token", recorder.Header().Get("Access-Control-Allow-Headers"))
+	require.Equal(t, "true", recorder.Header().Get("Access-Control-Allow-Credentials"))
+	require.Equal(t, "600", recorder.Header().Get("Access-Control-Max-Age"))
+	require.Equal(t, []string{"Origin", "Access-Control-Request-Method", "Access-Control-Request-Headers"}, recorder.Header()["Vary"])
+
+	// The origin pattern
+	recorder = preflight("https://a.b.example.org", "POST", "")
+	require.Equal(t, "https://a.b.example.org", recorder.Header().Get("Access-Control-Allow-Origin"))
+
+	// Disallowed origin, method or header
+	for _, args := range [][3]string{
+		{"https://evil.com", "POST", ""},
+		{"https://example.org", "POST", ""},
+		{"https://app.example.com", "PUT", ""},
+		{"https://app.example.com", "POST", "X-Other"},
+	} {
+		recorder = preflight(args[0], args[1], args[2])
+		require.Equal(t, http.StatusNoContent, recorder.Code)
+		require.Empty(t, recorder.Header().Get("Access-Control-Allow-Origin"), args)
+	}
+	require.Equal(t, 0, plugins)
+
+	// The actual requests including the error responses are decorated
+	serve := func(origin, token string) *httptest.ResponseRecorder {
+		request := httptest.NewRequest(http.MethodPost, "/", nil)
+		request.Header.Set("Origin", origin)
+		if token != "" {
+			request.Header.Set("X-Token", token)
+		}
+		recorder := httptest.NewRecorder()
+		handler.ServeHTTP(recorder, request)
+		return recorder
+	}
+	recorder = serve("https://app.example.com", "")
+	require.Equal(t, http.StatusUnauthorized, recorder.Code)
+	require.Equal(t, "https://app.example.com", recorder.Header().Get("Access-Control-Allow-Origin"))
+	require.Equal(t, "X-Request-ID", recorder.Header().Get("Access-Control-Expose-Headers"))
+	require.Equal(t, "Origin", recorder.Header().Get("Vary"))
+
+	recorder = serve("https://evil.com", "valid")
+	require.Empty(t, recorder.Header().Get("Access-Control-Allow-Origin"))
+	require.Equal(t, "Origin", recorder.Header().Get("Vary"))
+}
+
+func TestGlobalCORS(t *testing.T) {
+	SetCORS(&CORSOptions{AllowedOrigins: []string{"*"}})
+	defer SetCORS(nil)
+
+	handler := Wrap(func() error { return nil })
+	request := httptest.NewRequest(http.MethodGet, "/", nil)
+	request.Header.Set("Origin", "https://any.com")
+	recorder := httptest.NewRecorder()
+	handler.ServeHTTP(recorder, request)
+	require.Equal(t, "*", recorder.Header().Get("Access-Control-Allow-Origin"))
+
+	// The preflight of JSON-RPC
+	request = httptest.NewRequest(http.MethodOptions, "/rpc", nil)
+	request.Header.Set("Origin", "https://any.com")
+	request.Header.Set("Access-Control-Request-Method", "POST")
+	recorder = httptest.NewRecorder()
+	NewJSONRPC().ServeHTTP(recorder, request)
+	require.Equal(t, http.StatusNoContent, recorder.Code)
+	require.Equal(t, "*", recorder.Header().Get("Access-Control-Allow-Origin"))
+
+	// The group options override the global ones
+	handler = NewGroup().CORS(&CORSOptions{AllowedOrigins: []string{"https://app.com"}}).Wrap(func() error { return nil })
+	request = httptest.NewRequest(http.MethodGet, "/", nil)
+	request.Header.Set("Origin", "https://any.com")
+	recorder = httptest.NewRecorder()
+	handler.ServeHTTP(recorder, request)
+	require.Empty(t, recorder.Header().Get("Access-Control-Allow-Origin"))
+}
@@ -0,0 +1,260 @@
+// Copyright 2026 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package fn
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// ErrPreconditionFailed is returned (with status code 412) when the
+// preconditions of request (If-Match, If-Unmodified-Since and If-None-Match
+// of unsafe methods) are not satisfied
+var ErrPreconditionFailed = errors.New("precondition failed")
+
+type (
+	// ETagOptions configures the ETag generation
+	ETagOptions struct {
+		// Weak generates weak ETags, which only promise the responses are
+		// semantically equivalent
+		Weak bool
+	}
+
+	// ETagger is implemented by the response types supplying their own
+	// ETag, e.g. the version of resource, which is used instead of the
+	// generated one. The ETag is quoted if it isn't.
+	ETagger interface {
+		ETag() string
+	}
+
+	// LastModifier is implemented by the response types knowing when the
+	// resource was modified, which is sent as Last-Modified and compared
+	// with If-Modified-Since and If-Unmodified-Since
+	LastModifier interface {
+		LastModified() time.Time
+	}
+
+	// validated is the successful response of which the validators are
+	// evaluated
+	validated struct {
+		etag         string
+		lastModified time.Time
+		body         []byte
+		notModified  bool
+	}
+)
+
+var globalETag *ETagOptions
+
+// SetETag enables buffering the successful responses of GET and HEAD for all
+// wrapped handlers to generate strong ETags from their bodies, the requests
+// with matching If-None-Match, or If-Modified-Since not earlier than the
+// Last-Modified, are responded with 304, and the ones failing If-Match are
+// responded with 412. nil disables it, but the validators supplied by the
+// response types (see ETagger and LastModifier) are always honoured.
+func SetETag(opts *ETagOptions) {
+	globalETag = opts
+}
+
+// CheckPreconditions evaluates If-Match, If-Unmodified-Since and the
+// If-None-Match of unsafe methods against the current validators of resource,
+// the handlers of updates call it before modifying the resource, and return
+// the error (with status code 412) if the preconditions are not satisfied.
+// The lastModified is ignored if zero. The resource is regarded as existing,
+// so that "*" always matches it.
+//
+//	func update(r *http.Request, req *UpdateRequest) (*Order, error) {
+//		order := load(req.ID)
+//		if err := fn.CheckPreconditions(r, order.ETag(), order.UpdatedAt); err != nil {
+//			return nil, err
+//		}
+//		...
+//	}
+func CheckPreconditions(r *http.Request, etag string, lastModified time.Time) error {
+	if etag != "" {
+		etag = formatETag(etag)
+	}
+	if evaluatePreconditions(r, etag, lastModified) == http.StatusPreconditionFailed {
+		return ErrorWithStatusCode(ErrPreconditionFailed, http.StatusPreconditionFailed)
+	}
+	return nil
+}
+
+// validate computes the validators of successful response and evaluates the
+// preconditions of request, it returns nil if there is no validator
+func validate(ctx context.Context, r *http.Request, opts *ETagOptions, statusCode int, data interface{}) (*validated, error) {
+	if statusCode != 0 && statusCode != http.StatusOK || isEmptyPayload(data) {
+		return nil, nil
+	}
+
+	v := &validated{}
+	if t, ok := data.(ETagger); ok {
+		if etag := t.ETag(); etag != "" {
+			v.etag = formatETag(etag)
+		}
+	}
+	if m, ok := data.(LastModifier); ok {
+		v.lastModified = m.LastModified()
+	}
+	safe := r.Method == http.MethodGet || r.Method == http.MethodHead
+	if v.etag == "" && opts != nil && safe {
+		buf := &bytes.Buffer{}
+		if err := json.NewEncoder(buf).Encode(responseEncoder(ctx, data)); err != nil {
+			return nil, err
+		}
+		sum := sha256.Sum256(buf.Bytes())
+		v.etag = `"` + hex.EncodeToString(sum[:16]) + `"`
+		if opts.Weak {
+			v.etag = "W/" + v.etag
+		}
+		v.body = buf.Bytes()
+	}
+	if v.etag == "" && v.lastModified.IsZero() {
+		return nil, nil
+	}
+
+	// The responses of unsafe methods carry the validators of the new state,
+	// their preconditions are checked by the handlers before modifying
+	if safe {
+		switch evaluatePreconditions(r, v.etag, v.lastModified) {
+		case http.StatusPreconditionFailed:
+			return nil, ErrorWithStatusCode(ErrPreconditionFailed, http.StatusPreconditionFailed)
+		case http.StatusNotModified:
+			v.notModified = true
+		}
+	}
+	return v, nil
+}
+
+// write writes the validators and the response, the body is encoded unless
+// it has been buffered
+func (v *validated) write(ctx context.Context, w http.ResponseWriter, statusCode int, data interface{}) {
+	header := w.Header()
+	if v.etag != "" {
+		header.Set("ETag", v.etag)
+	}
+	if !v.lastModified.IsZero() {
+		header.Set("Last-Modified", v.lastModified.UTC().Format(http.TimeFormat))
+	}
+	if v.notModified {
+		header.Del("Content-Type")
+		w.WriteHeader(http.StatusNotModified)
+		return
+	}
+	if v.body == nil {
+		success(ctx, w, statusCode, data)
+		return
+	}
+	if statusCode != 0 {
+		w.WriteHeader(statusCode)
+	}
+	_, _ = w.Write(v.body)
+}
+
+// evaluatePreconditions evaluates the conditional headers in the order of
+// RFC 7232 section 6, it returns 304, 412 or 0 if the request should be
+// served normally
+func evaluatePreconditions(r *http.Request, etag string, lastModified time.Time) int {
+	safe := r.Method == http.MethodGet || r.Method == http.MethodHead
+	lastModified = lastModified.Truncate(time.Second)
+	if im := r.Header.Get("If-Match"); im != "" {
+		if !matchETag(im, etag, true) {
+			return http.StatusPreconditionFailed
+		}
+	} else if ius := r.Header.Get("If-Unmodified-Since"); ius != "" && !lastModified.IsZero() {
+		if t, err := http.ParseTime(ius); err == nil && lastModified.After(t) {
+			return http.StatusPreconditionFailed
+		}
+	}
+
+	if inm := r.Header.Get("If-None-Match"); inm != "" {
+		if matchETag(inm, etag, false) {
+			if safe {
+				return http.StatusNotModified
+			}
+			return http.StatusPreconditionFailed
+		}
+	} else if ims := r.Header.Get("If-Modified-Since"); ims != "" && safe && !lastModified.IsZero() {
+		if t, err := http.ParseTime(ims); err == nil && !lastModified.After(t) {
+			return http.StatusNotModified
+		}
+	}
+	return 0
+}
+
+// matchETag reports whether the list of ETags in the header matches the
+// etag, with the strong comparison if strong is true, or the weak one. "*"
+// matches the current representation even if it has no ETag.
+func matchETag(list, etag string, strong bool) bool {
+	if strings.TrimSpace(list) == "*" {
+		return true
+	}
+	if etag == "" {
+		return false
+	}
+	if strong && strings.HasPrefix(etag, "W/") {
+		return false
+	}
+	opaque := strings.TrimPrefix(etag, "W/")
+	for _, tag := range splitETags(list) {
+		if strings.HasPrefix(tag, "W/") {
+			if strong {
+				continue
+			}
+			tag = tag[2:]
+		}
+		if tag == opaque {
+			return true
+		}
+	}
+	return false
+}
+
+// splitETags splits the comma separated list of ETags, the commas inside the
+// quotes are kept
+func splitETags(list string) []string {
+	var (
+		tags   []string
+		quoted bool
+		start  int
+	)
+	for i := 0; i < len(list); i++ {
+		switch list[i] {
+		case '"':
+			quoted = !quoted
+		case ',':
+			if !quoted {
+				tags = append(tags, strings.TrimSpace(list[start:i]))
+				start = i + 1
+			}
+		}
+	}
+	return append(tags, strings.TrimSpace(list[start:]))
+}
+
+// formatETag quotes the etag if it isn't
+func formatETag(etag string) string {
+	if strings.HasPrefix(etag, `"`) || strings.HasPrefix(etag, `W/"`) {
+		return etag
+	}
+	return `"` + etag + `"`
+}
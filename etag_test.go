@@ -0,0 +1,181 @@
+// Copyright 2026 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package fn
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+type testVersioned struct {
+	Name    string    `json:"name"`
+	Version int       `json:"-"`
+	Updated time.Time `json:"-"`
+}
+
+func (v *testVersioned) ETag() string {
+	return "v" + strconv.Itoa(v.Version)
+}
+
+func (v *testVersioned) LastModified() time.Time {
+	return v.Updated
+}
+
+func TestETag(t *testing.T) {
+	SetResponseEncoder(func(ctx context.Context, payload interface{}) interface{} {
+		return payload
+	})
+	SetErrorEncoder(func(ctx context.Context, err error) interface{} {
+		return err.Error()
+	})
+
+	message := "hello"
+	handler := NewGroup().ETag(&ETagOptions{}).Wrap(func() (*testResponse, error) {
+		return &testResponse{Message: message}, nil
+	})
+	serve := func(method string, header map[string]string) *httptest.ResponseRecorder {
+		request := httptest.NewRequest(method, "/", nil)
+		for k, v := range header {
+			request.Header.Set(k, v)
+		}
+		recorder := httptest.NewRecorder()
+		handler.ServeHTTP(recorder, request)
+		return recorder
+	}
+
+	recorder := serve(http.MethodGet, nil)
+	require.Equal(t, http.StatusOK, recorder.Code)
+	require.JSONEq(t, `{"code":0,"message":"hello"}`, recorder.Body.String())
+	etag := recorder.Header().Get("ETag")
+	require.Regexp(t, `^"[0-9a-f]{32}"$`, etag)
+	require.Equal(t, etag, serve(http.MethodGet, nil).Header().Get("ETag"))
+
+	recorder = serve(http.MethodGet, map[string]string{"If-None-Match": `"other", ` + etag})
+	require.Equal(t, http.StatusNotModified, recorder.Code)
+	require.Equal(t, etag, recorder.Header().Get("ETag"))
+	require.Empty(t, recorder.Header().Get("Content-Type"))
+	require.Empty(t, recorder.Body.String())
+	require.Equal(t, http.StatusNotModified, serve(http.MethodGet, map[string]string{"If-None-Match": "W/" + etag}).Code)
+	require.Equal(t, http.StatusNotModified, serve(http.MethodGet, map[string]string{"If-None-Match": "*"}).Code)
+	require.Equal(t, http.StatusOK, serve(http.MethodGet, map[string]string{"If-Match": etag}).Code)
+
+	recorder = serve(http.MethodGet, map[string]string{"If-Match": `"other"`})
+	require.Equal(t, http.StatusPreconditionFailed, recorder.Code)
+	require.Equal(t, `"precondition failed"`+"\n", recorder.Body.String())
+
+	// The response has changed
+	message = "world"
+	recorder = serve(http.MethodGet, map[string]string{"If-None-Match": etag})
+	require.Equal(t, http.StatusOK, recorder.Code)
+	require.NotEqual(t, etag, recorder.Header().Get("ETag"))
+
+	// Not generated for unsafe methods
+	recorder = serve(http.MethodPost, map[string]string{"If-None-Match": "*"})
+	require.Equal(t, http.StatusOK, recorder.Code)
+	require.Empty(t, recorder.Header().Get("ETag"))
+
+	// Weakened if compressed
+	handler = NewGroup().ETag(&ETagOptions{}).Compression(&CompressionOptions{MinSize: 1}).Wrap(func() (*testResponse, error) {
+		return &testResponse{Message: message}, nil
+	})
+	recorder = serve(http.MethodGet, map[string]string{"Accept-Encoding": "gzip"})
+	require.Equal(t, "gzip", recorder.Header().Get("Content-Encoding"))
+	weak := recorder.Header().Get("ETag")
+	require.True(t, strings.HasPrefix(weak, `W/"`), weak)
+	require.Equal(t, http.StatusNotModified, serve(http.MethodGet, map[string]string{"Accept-Encoding": "gzip", "If-None-Match": weak}).Code)
+}
+
+func TestSuppliedValidators(t *testing.T) {
+	SetResponseEncoder(func(ctx context.Context, payload interface{}) interface{} {
+		return payload
+	})
+	SetErrorEncoder(func(ctx context.Context, err error) interface{} {
+		return err.Error()
+	})
+
+	updated := time.Date(2026, 1, 2, 3, 4, 5, 600, time.UTC)
+	current := &testVersioned{Name: "order", Version: 2, Updated: updated}
+	get := Wrap(func() (*testVersioned, error) {
+		return current, nil
+	})
+	update := Wrap(func(r *http.Request) (*testVersioned, error) {
+		if err := CheckPreconditions(r, current.ETag(), current.Updated); err != nil {
+			return nil, err
+		}
+		return &testVersioned{Name: "order", Version: current.Version + 1, Updated: updated.Add(time.Hour)}, nil
+	})
+	serve := func(handler http.Handler, method string, header map[string]string) *httptest.ResponseRecorder {
+		request := httptest.NewRequest(method, "/", strings.NewReader(`{}`))
+		for k, v := range header {
+			request.Header.Set(k, v)
+		}
+		recorder := httptest.NewRecorder()
+		handler.ServeHTTP(recorder, request)
+		return recorder
+	}
+
+	recorder := serve(get, http.MethodGet, nil)
+	require.Equal(t, http.StatusOK, recorder.Code)
+	require.Equal(t, `"v2"`, recorder.Header().Get("ETag"))
+	require.Equal(t, "Fri, 02 Jan 2026 03:04:05 GMT", recorder.Header().Get("Last-Modified"))
+
+	require.Equal(t, http.StatusNotModified, serve(get, http.MethodGet, map[string]string{"If-None-Match": `"v2"`}).Code)
+	require.Equal(t, http.StatusOK, serve(get, http.MethodGet, map[string]string{"If-None-Match": `"v1"`}).Code)
+	require.Equal(t, http.StatusNotModified, serve(get, http.MethodGet, map[string]string{"If-Modified-Since": "Fri, 02 Jan 2026 03:04:05 GMT"}).Code)
+	require.Equal(t, http.StatusOK, serve(get, http.MethodGet, map[string]string{"If-Modified-Since": "Fri, 02 Jan 2026 03:04:04 GMT"}).Code)
+	// If-None-Match takes precedence over If-Modified-Since
+	require.Equal(t, http.StatusOK, serve(get, http.MethodGet, map[string]string{
+		"If-None-Match":     `"v1"`,
+		"If-Modified-Since": "Fri, 02 Jan 2026 03:04:05 GMT",
+	}).Code)
+
+	recorder = serve(update, http.MethodPut, map[string]string{"If-Match": `"v2"`})
+	require.Equal(t, http.StatusOK, recorder.Code)
+	require.Equal(t, `"v3"`, recorder.Header().Get("ETag"))
+	require.Equal(t, http.StatusPreconditionFailed, serve(update, http.MethodPut, map[string]string{"If-Match": `"v1"`}).Code)
+	require.Equal(t, http.StatusPreconditionFailed, serve(update, http.MethodPut, map[string]string{"If-Match": `W/"v2"`}).Code)
+	require.Equal(t, http.StatusPreconditionFailed, serve(update, http.MethodPut, map[string]string{"If-None-Match": "*"}).Code)
+	require.Equal(t, http.StatusPreconditionFailed, serve(update, http.MethodPut, map[string]string{"If-Unmodified-Since": "Fri, 02 Jan 2026 03:00:00 GMT"}).Code)
+	require.Equal(t, http.StatusOK, serve(update, http.MethodPut, map[string]string{"If-Unmodified-Since": "Fri, 02 Jan 2026 03:04:05 GMT"}).Code)
+	require.Equal(t, http.StatusOK, serve(update, http.MethodPut, map[string]string{"If-Match": "*"}).Code)
+
+	// The resource with only Last-Modified matches "*"
+	touch := Wrap(func(r *http.Request) error {
+		return CheckPreconditions(r, "", current.Updated)
+	})
+	require.Equal(t, http.StatusNoContent, serve(touch, http.MethodPut, map[string]string{"If-Match": "*"}).Code)
+	require.Equal(t, http.StatusPreconditionFailed, serve(touch, http.MethodPut, map[string]string{"If-Match": `"v2"`}).Code)
+	require.Equal(t, http.StatusPreconditionFailed, serve(touch, http.MethodPut, map[string]string{"If-None-Match": "*"}).Code)
+}
+
+func TestMatchETag(t *testing.T) {
+	require.True(t, matchETag(`"a"`, `"a"`, true))
+	require.True(t, matchETag(`"x", "a"`, `"a"`, true))
+	require.True(t, matchETag(`"x,y", W/"a"`, `"a"`, false))
+	require.False(t, matchETag(`"x,y", W/"a"`, `"a"`, true))
+	require.False(t, matchETag(`"a"`, `W/"a"`, true))
+	require.True(t, matchETag(`"a"`, `W/"a"`, false))
+	require.True(t, matchETag(`*`, `"a"`, true))
+	require.True(t, matchETag(`*`, ``, true))
+	require.False(t, matchETag(`"a"`, ``, false))
+	require.Equal(t, []string{`"a,b"`, `W/"c"`, `"d"`}, splitETags(`"a,b", W/"c" ,"d"`))
+}
@@ -0,0 +1,280 @@
+// Copyright 2026 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package fn
+
+import (
+	"encoding"
+	"errors"
+	"fmt"
+	"net/url"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// FromForm marks a customized request type to be bound from request.Form
+// instead of being decoded from the JSON body. Embed it in the struct:
+//
+//	type ListOptions struct {
+//		fn.FromForm
+//		Page  int      `form:"page" default:"1"`
+//		Tags  []string `form:"tag"`
+//		Since time.Time `form:"since"`
+//	}
+//
+//	func list(ctx context.Context, opts *ListOptions, filter *Filter) (*Response, error)
+type FromForm struct{}
+
+var (
+	fromFormType        = reflect.TypeOf(FromForm{})
+	timeType            = reflect.TypeOf(time.Time{})
+	durationType        = reflect.TypeOf(time.Duration(0))
+	textUnmarshalerType = reflect.TypeOf((*encoding.TextUnmarshaler)(nil)).Elem()
+)
+
+// valueSource looks up the values associated with a key
+type valueSource func(key string) []string
+
+func urlValuesSource(values url.Values) valueSource {
+	return func(key string) []string {
+		return values[key]
+	}
+}
+
+// Bind binds the form values to the struct pointed by dst. Fields are matched
+// by the `form` tag (or the field name if absent), nested structs use dotted
+// keys (`filter.name`), slices accept repeated keys and `key[]`, and missing
+// values fall back to the `default` tag.
+func (f *uniform) Bind(dst interface{}) error {
+	return bind(urlValuesSource(f.Values), "form", dst)
+}
+
+// bind binds the values to the struct pointed by dst, the fields are matched
+// by the given tag key
+func bind(src valueSource, tag string, dst interface{}) error {
+	v := reflect.ValueOf(dst)
+	if v.Kind() != reflect.Ptr || v.IsNil() || indirectType(v.Type().Elem()).Kind() != reflect.Struct {
+		return errors.New("fn: bind destination should be a non-nil pointer to struct")
+	}
+	_, err := bindStruct(src, tag, "", indirect(v.Elem()), map[reflect.Type]bool{})
+	return err
+}
+
+// isFormBinding reports whether the type is a struct (or a pointer to
+// struct) which embeds FromForm
+func isFormBinding(t reflect.Type) bool {
+	if t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	if t.Kind() != reflect.Struct {
+		return false
+	}
+	for i := 0; i < t.NumField(); i++ {
+		if f := t.Field(i); f.Anonymous && f.Type == fromFormType {
+			return true
+		}
+	}
+	return false
+}
+
+// isScalarStruct reports whether the struct type is converted from a single
+// value rather than bound field by field
+func isScalarStruct(t reflect.Type) bool {
+	return t == timeType || reflect.PtrTo(t).Implements(textUnmarshalerType)
+}
+
+// bindStruct binds the fields of the struct and reports whether any field
+// has been set, visiting are the struct types being bound by the callers
+func bindStruct(src valueSource, tag, prefix string, v reflect.Value, visiting map[reflect.Type]bool) (bool, error) {
+	var bound bool
+	t := v.Type()
+	visiting[t] = true
+	defer delete(visiting, t)
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		fv := v.Field(i)
+		if field.Type == fromFormType {
+			continue
+		}
+
+		// The exported fields of an unexported embedded struct are still
+		// settable, so only skip the field itself when it cannot be reached
+		if !fv.CanSet() && !(field.Anonymous && fv.Kind() == reflect.Struct) {
+			continue
+		}
+
+		name := field.Tag.Get(tag)
+		if name == "-" {
+			continue
+		}
+
+		typ := field.Type
+		for typ.Kind() == reflect.Ptr {
+			typ = typ.Elem()
+		}
+
+		// Untagged embedded structs are flattened into the parent
+		if field.Anonymous && name == "" && typ.Kind() == reflect.Struct && !isScalarStruct(typ) {
+			ok, err := bindNested(src, tag, prefix, fv, visiting)
+			if err != nil {
+				return false, err
+			}
+			bound = bound || ok
+			continue
+		}
+
+		if name == "" {
+			name = field.Name
+		}
+		key := name
+		if prefix != "" {
+			key = prefix + "." + name
+		}
+
+		if typ.Kind() == reflect.Struct && !isScalarStruct(typ) {
+			ok, err := bindNested(src, tag, key, fv, visiting)
+			if err != nil {
+				return false, err
+			}
+			bound = bound || ok
+			continue
+		}
+
+		values := src(key)
+		if len(values) == 0 {
+			values = src(key + "[]")
+		}
+		if len(values) == 0 {
+			def, ok := field.Tag.Lookup("default")
+			if !ok {
+				continue
+			}
+			if typ.Kind() == reflect.Slice {
+				values = strings.Split(def, ",")
+			} else {
+				values = []string{def}
+			}
+		}
+
+		if err := setValues(fv, values, field.Tag.Get("layout")); err != nil {
+			return false, fmt.Errorf("fn: bind %s: %v", key, err)
+		}
+		bound = true
+	}
+	return bound, nil
+}
+
+// bindNested binds a nested struct field, the nil pointers are left
+// untouched unless some of the nested fields are present. The pointers to
+// the struct types being bound (e.g. type Node struct{ Next *Node }) are
+// not followed, otherwise the recursion never ends.
+func bindNested(src valueSource, tag, prefix string, v reflect.Value, visiting map[reflect.Type]bool) (bool, error) {
+	if v.Kind() == reflect.Ptr && visiting[indirectType(v.Type())] {
+		return false, nil
+	}
+	if v.Kind() != reflect.Ptr || !v.IsNil() {
+		return bindStruct(src, tag, prefix, indirect(v), visiting)
+	}
+	n := reflect.New(v.Type().Elem())
+	ok, err := bindStruct(src, tag, prefix, indirect(n.Elem()), visiting)
+	if ok && err == nil {
+		v.Set(n)
+	}
+	return ok, err
+}
+
+// indirect allocates the nil pointers and returns the value pointed to
+func indirect(v reflect.Value) reflect.Value {
+	for v.Kind() == reflect.Ptr {
+		if v.IsNil() {
+			v.Set(reflect.New(v.Type().Elem()))
+		}
+		v = v.Elem()
+	}
+	return v
+}
+
+func setValues(v reflect.Value, values []string, layout string) error {
+	v = indirect(v)
+	if v.Kind() == reflect.Slice && !v.Addr().Type().Implements(textUnmarshalerType) {
+		s := reflect.MakeSlice(v.Type(), len(values), len(values))
+		for i, value := range values {
+			if err := setValue(s.Index(i), value, layout); err != nil {
+				return err
+			}
+		}
+		v.Set(s)
+		return nil
+	}
+	return setValue(v, values[0], layout)
+}
+
+func setValue(v reflect.Value, value string, layout string) error {
+	v = indirect(v)
+	switch {
+	case v.Type() == timeType:
+		if layout == "" {
+			layout = time.RFC3339
+		}
+		t, err := time.Parse(layout, value)
+		if err != nil {
+			return err
+		}
+		v.Set(reflect.ValueOf(t))
+		return nil
+	case v.Type() == durationType:
+		d, err := time.ParseDuration(value)
+		if err != nil {
+			return err
+		}
+		v.SetInt(int64(d))
+		return nil
+	case v.Addr().Type().Implements(textUnmarshalerType):
+		return v.Addr().Interface().(encoding.TextUnmarshaler).UnmarshalText([]byte(value))
+	}
+
+	switch v.Kind() {
+	case reflect.String:
+		v.SetString(value)
+	case reflect.Bool:
+		b, err := strconv.ParseBool(value)
+		if err != nil {
+			return err
+		}
+		v.SetBool(b)
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		n, err := strconv.ParseInt(value, 10, v.Type().Bits())
+		if err != nil {
+			return err
+		}
+		v.SetInt(n)
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		n, err := strconv.ParseUint(value, 10, v.Type().Bits())
+		if err != nil {
+			return err
+		}
+		v.SetUint(n)
+	case reflect.Float32, reflect.Float64:
+		n, err := strconv.ParseFloat(value, v.Type().Bits())
+		if err != nil {
+			return err
+		}
+		v.SetFloat(n)
+	default:
+		return fmt.Errorf("unsupported type %s", v.Type())
+	}
+	return nil
+}
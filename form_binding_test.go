@@ -0,0 +1,148 @@
+// Copyright 2026 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package fn
+
+import (
+	"bytes"
+	"context"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+type testLevel int
+
+func (l *testLevel) UnmarshalText(text []byte) error {
+	switch string(text) {
+	case "low":
+		*l = 1
+	case "high":
+		*l = 2
+	default:
+		return ErrorWithStatusCode(errTest, http.StatusBadRequest)
+	}
+	return nil
+}
+
+type testPaging struct {
+	Page int `form:"page" default:"1"`
+	Size int `form:"size" default:"20"`
+}
+
+type testFilter struct {
+	Name  string    `form:"name"`
+	Level testLevel `form:"level"`
+}
+
+type testListOptions struct {
+	FromForm
+	testPaging
+	Tags     []string      `form:"tag"`
+	IDs      []int64       `form:"id"`
+	Sort     []string      `form:"sort" default:"name,id"`
+	Since    time.Time     `form:"since"`
+	Day      time.Time     `form:"day" layout:"2006-01-02"`
+	Timeout  time.Duration `form:"timeout"`
+	Verbose  *bool         `form:"verbose"`
+	Filter   testFilter    `form:"filter"`
+	Optional *testFilter   `form:"optional"`
+	Ignored  string        `form:"-"`
+	Keyword  string
+}
+
+func TestFormBind(t *testing.T) {
+	values, err := url.ParseQuery("tag=a&tag=b&id[]=1&id[]=2&since=2020-01-02T03:04:05Z&day=2020-01-02" +
+		"&timeout=1m30s&verbose=true&filter.name=foo&filter.level=high&Ignored=x&Keyword=kw&size=50")
+	require.NoError(t, err)
+
+	opts := &testListOptions{}
+	form := &Form{uniform{values}}
+	require.NoError(t, form.Bind(opts))
+
+	require.Equal(t, 1, opts.Page)
+	require.Equal(t, 50, opts.Size)
+	require.Equal(t, []string{"a", "b"}, opts.Tags)
+	require.Equal(t, []int64{1, 2}, opts.IDs)
+	require.Equal(t, []string{"name", "id"}, opts.Sort)
+	require.Equal(t, time.Date(2020, 1, 2, 3, 4, 5, 0, time.UTC), opts.Since)
+	require.Equal(t, time.Date(2020, 1, 2, 0, 0, 0, 0, time.UTC), opts.Day)
+	require.Equal(t, 90*time.Second, opts.Timeout)
+	require.NotNil(t, opts.Verbose)
+	require.True(t, *opts.Verbose)
+	require.Equal(t, testFilter{Name: "foo", Level: 2}, opts.Filter)
+	require.Nil(t, opts.Optional)
+	require.Empty(t, opts.Ignored)
+	require.Equal(t, "kw", opts.Keyword)
+}
+
+type testNode struct {
+	Name string    `form:"name"`
+	Next *testNode `form:"next"`
+}
+
+func TestFormBindRecursive(t *testing.T) {
+	node := &testNode{}
+	form := &Form{uniform{url.Values{"name": {"a"}, "next.name": {"b"}}}}
+	require.NoError(t, form.Bind(node))
+	require.Equal(t, "a", node.Name)
+	require.Nil(t, node.Next)
+}
+
+func TestFormBindError(t *testing.T) {
+	opts := &testListOptions{}
+	form := &Form{uniform{url.Values{"id": {"x"}}}}
+	err := form.Bind(opts)
+	require.Error(t, err)
+	require.True(t, strings.Contains(err.Error(), "id"))
+
+	form = &Form{uniform{url.Values{}}}
+	require.Error(t, form.Bind(testListOptions{}))
+}
+
+func TestFormBindingAdapter(t *testing.T) {
+	handler := Wrap(func(ctx context.Context, opts *testListOptions, req *testRequest) (*testResponse, error) {
+		return &testResponse{Code: opts.Page, Message: req.Foo + strings.Join(opts.Tags, ",")}, nil
+	})
+
+	recorder := httptest.NewRecorder()
+	request, err := http.NewRequest(http.MethodPost, "/?page=3&tag=a&tag=b", nil)
+	require.NoError(t, err)
+	request.Body = ioutil.NopCloser(bytes.NewBufferString(`{"foo":"hello"}`))
+	handler.ServeHTTP(recorder, request)
+	require.Equal(t, http.StatusOK, recorder.Code)
+	require.JSONEq(t, `{"code":3,"message":"helloa,b"}`, recorder.Body.String())
+
+	unary := Wrap(func(opts *testListOptions) (*testResponse, error) {
+		return &testResponse{Code: opts.Size}, nil
+	})
+	recorder = httptest.NewRecorder()
+	request, err = http.NewRequest(http.MethodGet, "/", nil)
+	require.NoError(t, err)
+	unary.ServeHTTP(recorder, request)
+	require.Equal(t, http.StatusOK, recorder.Code)
+	require.JSONEq(t, `{"code":20,"message":""}`, recorder.Body.String())
+
+	recorder = httptest.NewRecorder()
+	request, err = http.NewRequest(http.MethodGet, "/?filter.level=medium", nil)
+	require.NoError(t, err)
+	unary.ServeHTTP(recorder, request)
+	require.Equal(t, http.StatusBadRequest, recorder.Code)
+}
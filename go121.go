@@ -0,0 +1,169 @@
+//go:build go1.21
+// +build go1.21
+
+// Copyright 2026 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package fn
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"math/rand"
+	"net/http"
+	"time"
+)
+
+// AccessLog logs the requests served by the wrapped handlers with log/slog
+// after the response is written, so that the final status and latency are
+// known, which is impossible for a PluginFunc.
+//
+// The requests failed with 5xx are logged at error level, the slow requests
+// and the ones failed with 4xx are logged at warn level, and the others are
+// logged at info level if they are sampled.
+type AccessLog struct {
+	logger     *slog.Logger
+	message    string
+	sampleRate float64
+	slow       time.Duration
+	trustProxy bool
+}
+
+type accessLogProbe struct {
+	log *AccessLog
+	ctx context.Context
+}
+
+// NewAccessLog returns an access log writing to the logger, slog.Default()
+// if it is nil
+func NewAccessLog(logger *slog.Logger) *AccessLog {
+	return &AccessLog{
+		logger:     logger,
+		message:    "access",
+		sampleRate: 1,
+	}
+}
+
+// SetAccessLog enables logging the requests of all wrapped handlers, nil
+// disables it.
+//
+//	fn.SetAccessLog(fn.NewAccessLog(logger).Sample(0.1).SlowThreshold(time.Second))
+func SetAccessLog(l *AccessLog) {
+	if l == nil {
+		observers[accessLogSlot] = nil
+		return
+	}
+	observers[accessLogSlot] = l
+}
+
+// Message replaces the message of the log records, which is "access" by
+// default
+func (l *AccessLog) Message(msg string) *AccessLog {
+	l.message = msg
+	return l
+}
+
+// Sample logs the given fraction (between 0 and 1) of the successful
+// requests, the failed and slow requests are always logged
+func (l *AccessLog) Sample(rate float64) *AccessLog {
+	l.sampleRate = rate
+	return l
+}
+
+// SlowThreshold logs the requests taking longer than d at warn level, zero
+// disables it
+func (l *AccessLog) SlowThreshold(d time.Duration) *AccessLog {
+	l.slow = d
+	return l
+}
+
+// TrustProxy logs the client IP from the X-Forwarded-For (or X-Real-IP)
+// header, it should be enabled only behind a trusted proxy
+func (l *AccessLog) TrustProxy(trust bool) *AccessLog {
+	l.trustProxy = trust
+	return l
+}
+
+func (l *AccessLog) observe(ctx context.Context, x *exchange) (context.Context, probe) {
+	return ctx, &accessLogProbe{log: l, ctx: ctx}
+}
+
+func (p *accessLogProbe) beginPhase(ph phase)          {}
+func (p *accessLogProbe) endPhase(ph phase, err error) {}
+
+func (p *accessLogProbe) end(x *exchange) {
+	l := p.log
+	latency := x.elapsed()
+	status := x.status()
+	slow := l.slow > 0 && latency >= l.slow
+
+	level := slog.LevelInfo
+	switch {
+	case status >= http.StatusInternalServerError:
+		level = slog.LevelError
+	case status >= http.StatusBadRequest || slow:
+		level = slog.LevelWarn
+	case l.sampleRate < 1 && rand.Float64() >= l.sampleRate:
+		return
+	}
+
+	logger := l.logger
+	if logger == nil {
+		logger = slog.Default()
+	}
+	if !logger.Enabled(p.ctx, level) {
+		return
+	}
+
+	r := x.request
+	attrs := []slog.Attr{
+		slog.String("method", r.Method),
+		slog.String("route", x.name),
+		slog.String("path", r.URL.Path),
+		slog.Int("status", status),
+		slog.Duration("latency", latency),
+		slog.Int64("request_bytes", x.requestSize()),
+		slog.Int64("response_bytes", x.writer.written),
+		slog.String("client_ip", clientIP(r, l.trustProxy)),
+	}
+	if id := RequestIDFromContext(p.ctx); id != "" {
+		attrs = append(attrs, slog.String("request_id", id))
+	}
+	if slow {
+		attrs = append(attrs, slog.Bool("slow", true))
+	}
+	if x.err != nil {
+		attrs = append(attrs,
+			slog.String("error", x.err.Error()),
+			slog.String("error_class", errorClass(status, x.err)),
+		)
+	}
+	logger.LogAttrs(p.ctx, level, l.message, attrs...)
+}
+
+// errorClass classifies the error of a failed request: timeout, canceled,
+// server or client
+func errorClass(status int, err error) string {
+	switch {
+	case errors.Is(err, context.DeadlineExceeded):
+		return "timeout"
+	case errors.Is(err, context.Canceled):
+		return "canceled"
+	case status >= http.StatusInternalServerError:
+		return "server"
+	default:
+		return "client"
+	}
+}
@@ -0,0 +1,61 @@
+//go:build go1.21
+// +build go1.21
+
+// Copyright 2026 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package fn
+
+// The generic types require Go 1.21+ rather than 1.18+, since the earlier
+// versions don't raise the language version of the files by the build
+// constraints, which is go1.11 declared by go.mod.
+
+import "net/http"
+
+// Query binds the struct T from the URL query according to the `form` tags,
+// see Form.Bind for the supported fields.
+//
+//	func list(ctx context.Context, opts fn.Query[ListOptions], filter fn.Body[Filter]) (*Response, error)
+type Query[T any] struct {
+	Value T
+}
+
+func (q *Query[T]) bindRequest(r *http.Request, _ DecodeOption) error {
+	return bind(urlValuesSource(r.URL.Query()), "form", &q.Value)
+}
+
+func (q *Query[T]) fromBody() bool { return false }
+
+// Body decodes T from the JSON body
+type Body[T any] struct {
+	Value T
+}
+
+func (b *Body[T]) bindRequest(r *http.Request, opts DecodeOption) error {
+	return decodeJSON(r.Body, &b.Value, opts)
+}
+
+func (b *Body[T]) fromBody() bool { return true }
+
+// Headers binds the struct T from the request headers according to the
+// `header` tags, e.g. `header:"X-Tenant-ID"`.
+type Headers[T any] struct {
+	Value T
+}
+
+func (h *Headers[T]) bindRequest(r *http.Request, _ DecodeOption) error {
+	return bind(headerSource(r.Header), "header", &h.Value)
+}
+
+func (h *Headers[T]) fromBody() bool { return false }
@@ -0,0 +1,112 @@
+//go:build go1.21
+// +build go1.21
+
+// Copyright 2026 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package fn
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestAccessLog(t *testing.T) {
+	buf := &bytes.Buffer{}
+	logger := slog.New(slog.NewJSONHandler(buf, nil))
+	SetAccessLog(NewAccessLog(logger).SlowThreshold(20 * time.Millisecond).TrustProxy(true))
+	defer SetAccessLog(nil)
+	SetRequestID(&RequestIDOptions{})
+	defer SetRequestID(nil)
+
+	handler := Wrap(func(ctx context.Context, req *testRequest) (*testResponse, error) {
+		switch req.Foo {
+		case "slow":
+			time.Sleep(30 * time.Millisecond)
+		case "conflict":
+			return nil, ErrorWithStatusCode(errors.New("conflict"), http.StatusConflict)
+		case "timeout":
+			return nil, ErrorWithStatusCode(fmt.Errorf("query: %w", context.DeadlineExceeded), http.StatusGatewayTimeout)
+		}
+		return &testResponse{Message: req.Foo}, nil
+	}).Name("echo")
+
+	serve := func(foo string) map[string]interface{} {
+		buf.Reset()
+		request := httptest.NewRequest(http.MethodPost, "/echo", strings.NewReader(`{"foo":"`+foo+`"}`))
+		request.RemoteAddr = "10.0.0.1:1234"
+		request.Header.Set("X-Forwarded-For", "192.168.1.1, 10.0.0.2")
+		request.Header.Set("X-Request-ID", "req-1")
+		handler.ServeHTTP(httptest.NewRecorder(), request)
+		if buf.Len() == 0 {
+			return nil
+		}
+		record := map[string]interface{}{}
+		require.NoError(t, json.Unmarshal(buf.Bytes(), &record))
+		return record
+	}
+
+	record := serve("ok")
+	require.Equal(t, "INFO", record["level"])
+	require.Equal(t, "access", record["msg"])
+	require.Equal(t, "POST", record["method"])
+	require.Equal(t, "echo", record["route"])
+	require.Equal(t, "/echo", record["path"])
+	require.Equal(t, float64(http.StatusOK), record["status"])
+	require.Equal(t, float64(len(`{"foo":"ok"}`)), record["request_bytes"])
+	require.Equal(t, float64(len(`{"code":0,"message":"ok"}`+"\n")), record["response_bytes"])
+	require.Equal(t, "192.168.1.1", record["client_ip"])
+	require.Equal(t, "req-1", record["request_id"])
+	require.NotContains(t, record, "error")
+	require.NotContains(t, record, "slow")
+
+	record = serve("slow")
+	require.Equal(t, "WARN", record["level"])
+	require.Equal(t, true, record["slow"])
+	require.GreaterOrEqual(t, record["latency"], float64(20*time.Millisecond))
+
+	record = serve("conflict")
+	require.Equal(t, "WARN", record["level"])
+	require.Equal(t, float64(http.StatusConflict), record["status"])
+	require.Equal(t, "conflict", record["error"])
+	require.Equal(t, "client", record["error_class"])
+
+	record = serve("timeout")
+	require.Equal(t, "ERROR", record["level"])
+	require.Equal(t, "timeout", record["error_class"])
+
+	// The successful requests are sampled, the failed ones are always logged
+	SetAccessLog(NewAccessLog(logger).Sample(0).TrustProxy(false))
+	require.Nil(t, serve("ok"))
+	record = serve("conflict")
+	require.Equal(t, "10.0.0.1", record["client_ip"])
+}
+
+func TestErrorClass(t *testing.T) {
+	require.Equal(t, "timeout", errorClass(http.StatusServiceUnavailable, context.DeadlineExceeded))
+	require.Equal(t, "canceled", errorClass(http.StatusBadRequest, fmt.Errorf("read: %w", context.Canceled)))
+	require.Equal(t, "server", errorClass(http.StatusInternalServerError, errors.New("internal")))
+	require.Equal(t, "client", errorClass(http.StatusBadRequest, errors.New("invalid")))
+}
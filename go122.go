@@ -0,0 +1,41 @@
+//go:build go1.22
+// +build go1.22
+
+// Copyright 2026 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package fn
+
+import "net/http"
+
+// Path binds the struct T from the wildcards of the http.ServeMux pattern
+// according to the `path` tags.
+//
+//	http.Handle("GET /users/{id}", fn.Wrap(func(p fn.Path[struct {
+//		ID int64 `path:"id"`
+//	}]) (*User, error) {...}))
+type Path[T any] struct {
+	Value T
+}
+
+func (p *Path[T]) bindRequest(r *http.Request, _ DecodeOption) error {
+	return bind(func(key string) []string {
+		if v := r.PathValue(key); v != "" {
+			return []string{v}
+		}
+		return nil
+	}, "path", &p.Value)
+}
+
+func (p *Path[T]) fromBody() bool { return false }
@@ -14,9 +14,22 @@
 
 package fn
 
+import "time"
+
 // Group represents a handler group that contains same hooks
 type Group struct {
-	plugins []PluginFunc
+	plugins       []PluginFunc
+	bodyLimit     int64
+	decodeOptions *DecodeOption
+	timeout       time.Duration
+	concurrency   *ConcurrencyLimiter
+	requirements  []string
+	cors          *cors
+	compression   *CompressionOptions
+	etag          *ETagOptions
+	cache         *CacheOptions
+	idempotency   *IdempotencyOptions
+	collapse      *CollapseOptions
 }
 
 func NewGroup() *Group {
@@ -32,8 +45,96 @@ func (g *Group) Plugin(plugins ...PluginFunc) *Group {
 	return g
 }
 
+// BodyLimit limits the size of request body in bytes for the handlers
+// wrapped by the group, which overrides the global limit. Negative means
+// unlimited.
+func (g *Group) BodyLimit(n int64) *Group {
+	g.bodyLimit = n
+	return g
+}
+
+// DecodeOptions sets the options of decoding JSON request body for the
+// handlers wrapped by the group, which overrides the global ones.
+func (g *Group) DecodeOptions(opts ...DecodeOption) *Group {
+	g.decodeOptions = makeDecodeOption(opts)
+	return g
+}
+
+// Timeout sets the timeout of the handlers wrapped by the group, which
+// overrides the global timeout. Negative means no timeout.
+func (g *Group) Timeout(d time.Duration) *Group {
+	g.timeout = d
+	return g
+}
+
+// Concurrency limits the number of requests served concurrently by all
+// handlers wrapped by the group, which share the limiter
+func (g *Group) Concurrency(l *ConcurrencyLimiter) *Group {
+	g.concurrency = l
+	return g
+}
+
+// Require adds the requirements of all handlers wrapped by the group
+func (g *Group) Require(requirements ...string) *Group {
+	g.requirements = append(g.requirements, requirements...)
+	return g
+}
+
+// CORS enables CORS for the handlers wrapped by the group, which overrides
+// the global CORS options
+func (g *Group) CORS(opts *CORSOptions) *Group {
+	g.cors = newCORS(opts)
+	return g
+}
+
+// Compression enables the compression for the handlers wrapped by the
+// group, which overrides the global options
+func (g *Group) Compression(opts *CompressionOptions) *Group {
+	g.compression = opts
+	return g
+}
+
+// ETag enables generating ETags for the handlers wrapped by the group, which
+// overrides the global options
+func (g *Group) ETag(opts *ETagOptions) *Group {
+	g.etag = opts
+	return g
+}
+
+// Cache enables caching the successful responses of GET for the handlers
+// wrapped by the group
+func (g *Group) Cache(opts *CacheOptions) *Group {
+	g.cache = opts
+	return g
+}
+
+// Idempotency enables deduplicating the unsafe requests by the
+// Idempotency-Key header for the handlers wrapped by the group
+func (g *Group) Idempotency(opts *IdempotencyOptions) *Group {
+	g.idempotency = opts
+	return g
+}
+
+// Collapse enables sharing a single invocation among the concurrent
+// identical GET requests for the handlers wrapped by the group
+func (g *Group) Collapse(opts *CollapseOptions) *Group {
+	g.collapse = opts
+	return g
+}
+
 func (g *Group) Wrap(f interface{}) *fn {
 	n := Wrap(f)
+	n.bodyLimit = g.bodyLimit
+	n.decodeOptions = g.decodeOptions
+	n.timeout = g.timeout
+	n.concurrency = g.concurrency
+	n.requirements = append(n.requirements, g.requirements...)
+	n.cors = g.cors
+	n.compression = g.compression
+	n.etag = g.etag
+	n.cache = g.cache
+	n.idempotency = g.idempotency
+	n.collapse = g.collapse
 	if length := len(g.plugins); length > 0 {
 		n.plugins = make([]PluginFunc, length)
 		copy(n.plugins, g.plugins)
@@ -0,0 +1,250 @@
+// Copyright 2026 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package fn
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"io/ioutil"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// IdempotencyKeyHeader is the request header carrying the idempotency key
+const IdempotencyKeyHeader = "Idempotency-Key"
+
+const (
+	defaultIdempotencyTTL   = 24 * time.Hour
+	maxIdempotencyKeyLength = 255
+	idempotencyPollInterval = 20 * time.Millisecond
+)
+
+var (
+	// ErrIdempotencyKeyMissing is returned (with status code 400) when the
+	// idempotency key is required but absent or invalid
+	ErrIdempotencyKeyMissing = errors.New("idempotency key is missing or invalid")
+	// ErrIdempotencyKeyInUse is returned (with status code 409) when the
+	// request with the same idempotency key is still being processed
+	ErrIdempotencyKeyInUse = errors.New("idempotency key is in use")
+	// ErrIdempotencyKeyReused is returned (with status code 422) when the
+	// idempotency key has been used by a different request
+	ErrIdempotencyKeyReused = errors.New("idempotency key is reused by a different request")
+)
+
+type (
+	// IdempotencyOptions configures the idempotency of unsafe requests (e.g.
+	// POST) carrying the Idempotency-Key header
+	IdempotencyOptions struct {
+		// TTL is how long the responses are kept for retries, 24 hours if
+		// zero
+		TTL time.Duration
+		// Wait is how long the duplicates wait for the request in flight to
+		// complete and replay its response, they are rejected with 409
+		// immediately if zero
+		Wait time.Duration
+		// Required rejects the requests without the key with 400
+		Required bool
+	}
+
+	// IdempotencyRecord is the state of an idempotency key
+	IdempotencyRecord struct {
+		// Fingerprint identifies the request which claimed the key
+		Fingerprint string
+		// Response is the response to replay, or nil if the request is in
+		// flight
+		Response *CachedResponse
+	}
+
+	// IdempotencyStore stores the states of idempotency keys, the in-memory
+	// store is used by default, a shared store (e.g. Redis) is required to
+	// deduplicate the requests across instances.
+	IdempotencyStore interface {
+		// Claim claims the key for the request atomically, it returns nil
+		// if the key is claimed, or the existing record of the key
+		Claim(ctx context.Context, key, fingerprint string, ttl time.Duration) (*IdempotencyRecord, error)
+		// Complete records the response of the claimed key
+		Complete(ctx context.Context, key string, resp *CachedResponse, ttl time.Duration) error
+		// Release releases the claimed key without response, so that the
+		// request can be retried
+		Release(ctx context.Context, key string) error
+	}
+
+	// MemoryIdempotencyStore is an IdempotencyStore in memory, the expired
+	// records are evicted periodically
+	MemoryIdempotencyStore struct {
+		mu      sync.Mutex
+		records map[string]*memoryIdempotencyRecord
+		swept   time.Time
+		now     func() time.Time
+	}
+
+	memoryIdempotencyRecord struct {
+		IdempotencyRecord
+		expires time.Time
+	}
+
+	// idempotentRequest is the request which claimed its key
+	idempotentRequest struct {
+		key  string
+		ttl  time.Duration
+		rec  *cacheRecorder
+		from time.Time
+	}
+)
+
+var idempotencyStore IdempotencyStore = NewMemoryIdempotencyStore()
+
+// SetIdempotencyStore sets where the states of idempotency keys are stored,
+// an in-memory store by default
+func SetIdempotencyStore(store IdempotencyStore) {
+	idempotencyStore = store
+}
+
+// beginIdempotent claims the idempotency key of request, which is scoped by
+// the principal, method and path. It returns the response to replay if the
+// key has been completed by the same request, or the claimed request whose
+// response is recorded. The requests without the key are served as usual.
+func beginIdempotent(ctx context.Context, opts *IdempotencyOptions, w http.ResponseWriter, r *http.Request) (*CachedResponse, *idempotentRequest, error) {
+	if r.Method == http.MethodGet || r.Method == http.MethodHead || r.Method == http.MethodOptions {
+		return nil, nil, nil
+	}
+	key := r.Header.Get(IdempotencyKeyHeader)
+	if key == "" || len(key) > maxIdempotencyKeyLength {
+		if opts.Required || key != "" {
+			return nil, nil, ErrorWithStatusCode(ErrIdempotencyKeyMissing, http.StatusBadRequest)
+		}
+		return nil, nil, nil
+	}
+
+	// The body is buffered for the fingerprint, and read again by decoding
+	var body []byte
+	if r.Body != nil && r.Body != http.NoBody {
+		var err error
+		if body, err = ioutil.ReadAll(r.Body); err != nil {
+			return nil, nil, err
+		}
+		r.Body = ioutil.NopCloser(bytes.NewReader(body))
+	}
+	h := sha256.New()
+	h.Write([]byte(r.Method + " " + r.URL.RequestURI() + "\n"))
+	h.Write(body)
+	fingerprint := hex.EncodeToString(h.Sum(nil))
+
+	principal, _ := PrincipalFromContext(ctx)
+	sum := sha256.Sum256([]byte(principal + "\n" + r.Method + " " + r.URL.Path + "\n" + key))
+	scoped := hex.EncodeToString(sum[:])
+	ttl := opts.TTL
+	if ttl <= 0 {
+		ttl = defaultIdempotencyTTL
+	}
+
+	deadline := time.Now().Add(opts.Wait)
+	for {
+		record, err := idempotencyStore.Claim(ctx, scoped, fingerprint, ttl)
+		switch {
+		case err != nil:
+			return nil, nil, ErrorWithStatusCode(err, http.StatusServiceUnavailable)
+		case record == nil:
+			return nil, &idempotentRequest{key: scoped, ttl: ttl, rec: newCacheRecorder(w), from: time.Now()}, nil
+		case record.Fingerprint != fingerprint:
+			return nil, nil, ErrorWithStatusCode(ErrIdempotencyKeyReused, http.StatusUnprocessableEntity)
+		case record.Response != nil:
+			return record.Response, nil, nil
+		case !time.Now().Before(deadline):
+			return nil, nil, ErrorWithStatusCode(ErrIdempotencyKeyInUse, http.StatusConflict)
+		}
+
+		select {
+		case <-ctx.Done():
+			return nil, nil, ctx.Err()
+		case <-time.After(idempotencyPollInterval):
+		}
+	}
+}
+
+// finish records the response, or releases the key if the request failed
+// by the server errors or panics, so that it can be retried. The request
+// may have been canceled, so that the store is accessed without its
+// context.
+func (ir *idempotentRequest) finish() {
+	status := ir.rec.status
+	if status == 0 || status >= http.StatusInternalServerError {
+		_ = idempotencyStore.Release(context.Background(), ir.key)
+		return
+	}
+	_ = idempotencyStore.Complete(context.Background(), ir.key, ir.rec.recorded(ir.from), ir.ttl)
+}
+
+// writeReplay writes the response recorded by the first request
+func writeReplay(w http.ResponseWriter, resp *CachedResponse) {
+	_ = writeRecorded(w, nil, resp, http.Header{"Idempotent-Replayed": {"true"}})
+}
+
+// NewMemoryIdempotencyStore returns an empty in-memory store
+func NewMemoryIdempotencyStore() *MemoryIdempotencyStore {
+	return &MemoryIdempotencyStore{
+		records: map[string]*memoryIdempotencyRecord{},
+		now:     time.Now,
+	}
+}
+
+// Claim implements the IdempotencyStore interface
+func (s *MemoryIdempotencyStore) Claim(ctx context.Context, key, fingerprint string, ttl time.Duration) (*IdempotencyRecord, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := s.now()
+	if now.Sub(s.swept) >= memoryStoreSweepInterval {
+		for k, record := range s.records {
+			if now.After(record.expires) {
+				delete(s.records, k)
+			}
+		}
+		s.swept = now
+	}
+
+	if record, ok := s.records[key]; ok && now.Before(record.expires) {
+		existing := record.IdempotencyRecord
+		return &existing, nil
+	}
+	s.records[key] = &memoryIdempotencyRecord{
+		IdempotencyRecord: IdempotencyRecord{Fingerprint: fingerprint},
+		expires:           now.Add(ttl),
+	}
+	return nil, nil
+}
+
+// Complete implements the IdempotencyStore interface
+func (s *MemoryIdempotencyStore) Complete(ctx context.Context, key string, resp *CachedResponse, ttl time.Duration) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if record, ok := s.records[key]; ok {
+		record.Response = resp
+		record.expires = s.now().Add(ttl)
+	}
+	return nil
+}
+
+// Release implements the IdempotencyStore interface
+func (s *MemoryIdempotencyStore) Release(ctx context.Context, key string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.records, key)
+	return nil
+}
@@ -0,0 +1,190 @@
+// Copyright 2026 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package fn
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestIdempotency(t *testing.T) {
+	SetResponseEncoder(func(ctx context.Context, payload interface{}) interface{} {
+		return payload
+	})
+	SetErrorEncoder(func(ctx context.Context, err error) interface{} {
+		return err.Error()
+	})
+	SetIdempotencyStore(NewMemoryIdempotencyStore())
+	defer SetIdempotencyStore(NewMemoryIdempotencyStore())
+
+	calls := 0
+	handler := NewGroup().Idempotency(&IdempotencyOptions{}).Plugin(func(ctx context.Context, r *http.Request) (context.Context, error) {
+		return WithPrincipal(ctx, r.Header.Get("X-User")), nil
+	}).Wrap(func(ctx context.Context, req *testRequest) (*testResponse, error) {
+		calls++
+		switch req.Foo {
+		case "invalid":
+			return nil, fmt.Errorf("invalid %d", calls)
+		case "unavailable":
+			return nil, ErrorWithStatusCode(errors.New("unavailable"), http.StatusServiceUnavailable)
+		}
+		ResponseHeader(ctx).Set("Location", fmt.Sprintf("/orders/%d", calls))
+		return &testResponse{Message: fmt.Sprintf("%s %d", req.Foo, calls)}, nil
+	})
+	serve := func(method, key, user, body string) *httptest.ResponseRecorder {
+		request := httptest.NewRequest(method, "/orders", strings.NewReader(body))
+		if key != "" {
+			request.Header.Set(IdempotencyKeyHeader, key)
+		}
+		request.Header.Set("X-User", user)
+		recorder := httptest.NewRecorder()
+		handler.ServeHTTP(recorder, request)
+		return recorder
+	}
+
+	recorder := serve(http.MethodPost, "k1", "alice", `{"foo":"order"}`)
+	require.Equal(t, http.StatusOK, recorder.Code)
+	require.JSONEq(t, `{"code":0,"message":"order 1"}`, recorder.Body.String())
+	require.Empty(t, recorder.Header().Get("Idempotent-Replayed"))
+
+	// Replayed for the retries
+	recorder = serve(http.MethodPost, "k1", "alice", `{"foo":"order"}`)
+	require.Equal(t, http.StatusOK, recorder.Code)
+	require.JSONEq(t, `{"code":0,"message":"order 1"}`, recorder.Body.String())
+	require.Equal(t, "true", recorder.Header().Get("Idempotent-Replayed"))
+	require.Equal(t, "/orders/1", recorder.Header().Get("Location"))
+	require.Equal(t, "application/json; charset=utf-8", recorder.Header().Get("Content-Type"))
+	require.Equal(t, 1, calls)
+
+	// Reused by a different request
+	recorder = serve(http.MethodPost, "k1", "alice", `{"foo":"other"}`)
+	require.Equal(t, http.StatusUnprocessableEntity, recorder.Code)
+	require.Equal(t, `"idempotency key is reused by a different request"`+"\n", recorder.Body.String())
+
+	// Scoped by the principal
+	require.JSONEq(t, `{"code":0,"message":"order 2"}`, serve(http.MethodPost, "k1", "bob", `{"foo":"order"}`).Body.String())
+
+	// Without the key
+	require.JSONEq(t, `{"code":0,"message":"order 3"}`, serve(http.MethodPost, "", "alice", `{"foo":"order"}`).Body.String())
+	require.JSONEq(t, `{"code":0,"message":"order 4"}`, serve(http.MethodPost, "", "alice", `{"foo":"order"}`).Body.String())
+	require.Equal(t, http.StatusBadRequest, serve(http.MethodPost, strings.Repeat("k", 256), "alice", `{"foo":"order"}`).Code)
+
+	// The client errors are replayed, and the server errors are retried
+	require.Equal(t, `"invalid 5"`+"\n", serve(http.MethodPost, "k2", "alice", `{"foo":"invalid"}`).Body.String())
+	recorder = serve(http.MethodPost, "k2", "alice", `{"foo":"invalid"}`)
+	require.Equal(t, http.StatusBadRequest, recorder.Code)
+	require.Equal(t, `"invalid 5"`+"\n", recorder.Body.String())
+	require.Equal(t, http.StatusServiceUnavailable, serve(http.MethodPost, "k3", "alice", `{"foo":"unavailable"}`).Code)
+	require.Equal(t, http.StatusServiceUnavailable, serve(http.MethodPost, "k3", "alice", `{"foo":"unavailable"}`).Code)
+	require.Equal(t, 7, calls)
+
+	handler = NewGroup().Idempotency(&IdempotencyOptions{Required: true}).Wrap(func(req *testRequest) (*testResponse, error) {
+		return &testResponse{Message: req.Foo}, nil
+	})
+	require.Equal(t, http.StatusBadRequest, serve(http.MethodPost, "", "alice", `{"foo":"order"}`).Code)
+	require.Equal(t, http.StatusOK, serve(http.MethodPost, "k4", "alice", `{"foo":"order"}`).Code)
+}
+
+func TestIdempotencyInFlight(t *testing.T) {
+	SetResponseEncoder(func(ctx context.Context, payload interface{}) interface{} {
+		return payload
+	})
+	SetErrorEncoder(func(ctx context.Context, err error) interface{} {
+		return err.Error()
+	})
+	SetIdempotencyStore(NewMemoryIdempotencyStore())
+	defer SetIdempotencyStore(NewMemoryIdempotencyStore())
+
+	var calls int32
+	started := make(chan struct{}, 1)
+	release := make(chan struct{})
+	f := func(req *testRequest) (*testResponse, error) {
+		n := atomic.AddInt32(&calls, 1)
+		started <- struct{}{}
+		<-release
+		return &testResponse{Message: fmt.Sprintf("%s %d", req.Foo, n)}, nil
+	}
+	serve := func(handler http.Handler, results chan<- *httptest.ResponseRecorder) {
+		request := httptest.NewRequest(http.MethodPost, "/orders", strings.NewReader(`{"foo":"order"}`))
+		request.Header.Set(IdempotencyKeyHeader, "k1")
+		recorder := httptest.NewRecorder()
+		handler.ServeHTTP(recorder, request)
+		results <- recorder
+	}
+
+	rejecting := Wrap(f).Idempotency(&IdempotencyOptions{})
+	waiting := Wrap(f).Idempotency(&IdempotencyOptions{Wait: 5 * time.Second})
+	first := make(chan *httptest.ResponseRecorder, 1)
+	go serve(rejecting, first)
+	<-started
+
+	// Rejected immediately without waiting
+	rejected := make(chan *httptest.ResponseRecorder, 1)
+	serve(rejecting, rejected)
+	recorder := <-rejected
+	require.Equal(t, http.StatusConflict, recorder.Code)
+	require.Equal(t, `"idempotency key is in use"`+"\n", recorder.Body.String())
+
+	// Waits for the request in flight and replays its response
+	waited := make(chan *httptest.ResponseRecorder, 1)
+	go serve(waiting, waited)
+	time.Sleep(3 * idempotencyPollInterval)
+	close(release)
+
+	recorder = <-first
+	require.Equal(t, http.StatusOK, recorder.Code)
+	require.JSONEq(t, `{"code":0,"message":"order 1"}`, recorder.Body.String())
+	recorder = <-waited
+	require.Equal(t, http.StatusOK, recorder.Code)
+	require.JSONEq(t, `{"code":0,"message":"order 1"}`, recorder.Body.String())
+	require.Equal(t, "true", recorder.Header().Get("Idempotent-Replayed"))
+	require.Equal(t, int32(1), atomic.LoadInt32(&calls))
+}
+
+func TestMemoryIdempotencyStore(t *testing.T) {
+	ctx := context.Background()
+	now := time.Now()
+	store := NewMemoryIdempotencyStore()
+	store.now = func() time.Time { return now }
+
+	record, err := store.Claim(ctx, "k", "a", time.Minute)
+	require.NoError(t, err)
+	require.Nil(t, record)
+	record, _ = store.Claim(ctx, "k", "b", time.Minute)
+	require.Equal(t, &IdempotencyRecord{Fingerprint: "a"}, record)
+
+	resp := &CachedResponse{StatusCode: http.StatusCreated}
+	require.NoError(t, store.Complete(ctx, "k", resp, time.Hour))
+	record, _ = store.Claim(ctx, "k", "a", time.Minute)
+	require.Equal(t, resp, record.Response)
+
+	// Expired
+	now = now.Add(time.Hour)
+	record, _ = store.Claim(ctx, "k", "c", time.Minute)
+	require.Nil(t, record)
+
+	require.NoError(t, store.Release(ctx, "k"))
+	record, _ = store.Claim(ctx, "k", "d", time.Minute)
+	require.Nil(t, record)
+}
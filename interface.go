@@ -27,10 +27,15 @@ func Wrap(f interface{}) *fn {
 	numOut := t.NumOut()
 
 	// Supported signatures
+	// func(...) error
 	// func(...) (Response, error)
-	if numOut != 2 {
+	// func(...) (Response, int, error)
+	if numOut < 1 || numOut > 3 || t.Out(numOut-1) != errorType {
 		panic("unsupported function type, function return values should contain response data & error")
 	}
+	if numOut == 3 && t.Out(1).Kind() != reflect.Int {
+		panic("unsupported function type, the status code should be an int")
+	}
 
 	var (
 		adapter   adapter
@@ -60,21 +65,22 @@ func Wrap(f interface{}) *fn {
 		adapter = &simplePlainAdapter{
 			inContext: false,
 			method:    reflect.ValueOf(f),
-			cacheArgs: []reflect.Value{},
 		}
 	} else if numIn == 1 && inContext {
 		// func(ctx context.Context) (Response, error)
 		adapter = &simplePlainAdapter{
 			inContext: true,
 			method:    reflect.ValueOf(f),
-			cacheArgs: make([]reflect.Value, 1),
 		}
-	} else if numIn == 1 && !isBuiltinType(t.In(0)) && t.In(0).Kind() == reflect.Ptr {
+	} else if numIn == 1 && !isBuiltinType(t.In(0)) && !isContextValueType(t.In(0)) && !isFormBinding(t.In(0)) && !isBinder(t.In(0)) {
 		// func(request *Customized) (Response, error)
+		// func(request Customized) (Response, error)
+		// func(items []Item) (Response, error)
+		// func(settings map[string]Setting) (Response, error)
+		checkCustomizedType(t.In(0))
 		adapter = &simpleUnaryAdapter{
-			argType:   t.In(0),
-			method:    reflect.ValueOf(f),
-			cacheArgs: make([]reflect.Value, 1),
+			argType: t.In(0),
+			method:  reflect.ValueOf(f),
 		}
 	} else {
 		// Complicated signatures
@@ -87,10 +93,12 @@ func Wrap(f interface{}) *fn {
 		// func (form fn.Form) (*LoginResponse, error) {}
 		// func (header http.Header, form fn.Form, body io.ReadCloser) (*LoginResponse, error) {}
 		// func (header http.Header, r *LoginRequest, url *url.URL) (*LoginResponse, error) { }
+		// func (opts *ListOptions, r *LoginRequest) (*LoginResponse, error) { } // ListOptions embeds fn.FromForm
+		// func (opts fn.Query[ListOptions], r fn.Body[LoginRequest]) (*LoginResponse, error) { }
 		adapter = makeGenericAdapter(reflect.ValueOf(f), inContext)
 	}
 
-	return &fn{adapter: adapter}
+	return &fn{name: funcName(f), adapter: adapter}
 }
 
 func SetErrorEncoder(c ErrorEncoder) {
@@ -110,3 +118,15 @@ func SetResponseEncoder(c ResponseEncoder) {
 func SetMultipartFormMaxMemory(m int64) {
 	maxMemory = m
 }
+
+// SetRequestBodyLimit sets the default limit of request body in bytes,
+// the oversize bodies are rejected with 413 when decoding JSON, parsing
+// form or multipart form. Zero or negative means unlimited.
+func SetRequestBodyLimit(n int64) {
+	bodyLimit = n
+}
+
+// SetDecodeOptions sets the default options of decoding JSON request body
+func SetDecodeOptions(opts ...DecodeOption) {
+	decodeOptions = *makeDecodeOption(opts)
+}
@@ -0,0 +1,408 @@
+// Copyright 2026 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package fn
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"reflect"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Standard JSON-RPC 2.0 error codes
+const (
+	JSONRPCParseError     = -32700
+	JSONRPCInvalidRequest = -32600
+	JSONRPCMethodNotFound = -32601
+	JSONRPCInvalidParams  = -32602
+	JSONRPCInternalError  = -32603
+	// JSONRPCServerError is used for the handler errors without status code
+	JSONRPCServerError = -32000
+)
+
+type (
+	// JSONRPC represents a JSON-RPC 2.0 endpoint, the registered functions
+	// are wrapped in the same way as Wrap, so that they accept the same
+	// signatures and run the same plugins and requirements.
+	//
+	// The calls run the plugins, the decoding and the function within the
+	// timeout (which cancels the context of call) and the concurrency limit
+	// of handlers. The other settings of handlers (cache, idempotency, ETag
+	// and compression) and the observers (metrics, tracing and access log)
+	// are not applied to them. The headers set by
+	// ResponseHeader in any call (e.g. WWW-Authenticate and Retry-After) are
+	// sent with the response of the whole request.
+	JSONRPC struct {
+		mu      sync.RWMutex
+		methods map[string]*rpcMethod
+	}
+
+	// JSONRPCError represents the error object of JSON-RPC 2.0 response
+	JSONRPCError struct {
+		Code    int         `json:"code"`
+		Message string      `json:"message"`
+		Data    interface{} `json:"data,omitempty"`
+	}
+
+	rpcMethod struct {
+		handler *fn
+		// whether the positional params are passed as a whole, which is true
+		// if the body of handler is decoded into a slice or array
+		positional bool
+		// the JSON names of the fields which the positional params are
+		// decoded into in order, nil if the body is not a struct
+		fields []string
+	}
+
+	rpcRequest struct {
+		id     json.RawMessage // nil for notifications
+		method string
+		params json.RawMessage
+	}
+
+	rpcResponse struct {
+		Version string          `json:"jsonrpc"`
+		Result  interface{}     `json:"result,omitempty"`
+		Error   *JSONRPCError   `json:"error,omitempty"`
+		ID      json.RawMessage `json:"id"`
+	}
+)
+
+var nullID = json.RawMessage("null")
+
+func (e *JSONRPCError) Error() string {
+	return e.Message
+}
+
+// NewJSONRPC returns a JSON-RPC 2.0 endpoint without methods
+func NewJSONRPC() *JSONRPC {
+	return &JSONRPC{methods: map[string]*rpcMethod{}}
+}
+
+// Register registers the function by the method name, the named params are
+// decoded as the body of the function. The positional params are decoded as a
+// whole if the function accepts a slice, into the fields of struct in the
+// declaration order if it accepts a struct, otherwise they can contain at
+// most one element which is decoded as the body. The extra elements are
+// invalid params. The handlers returned by Wrap and Group.Wrap are also
+// accepted, with their timeout and concurrency limit.
+func (j *JSONRPC) Register(method string, f interface{}) *JSONRPC {
+	handler, ok := f.(*fn)
+	if !ok {
+		handler = Wrap(f).Name(method)
+	}
+
+	m := &rpcMethod{handler: handler}
+	if t := bodyType(handler.adapter); t != nil {
+		switch t = indirectType(t); t.Kind() {
+		case reflect.Slice, reflect.Array:
+			m.positional = true
+		case reflect.Struct:
+			m.fields = jsonFields(t)
+		}
+	}
+
+	j.mu.Lock()
+	j.methods[method] = m
+	j.mu.Unlock()
+	return j
+}
+
+func (j *JSONRPC) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if handleCORS(nil, w, r) {
+		return
+	}
+	w.Header().Set("Content-Type", "application/json; charset=utf-8")
+	r = withRequestID(w, r)
+	limit := effectiveLimit(0, bodyLimit)
+	if err := limitBody(w, r, limit); err != nil {
+		failure(r.Context(), w, err)
+		return
+	}
+	if c := globalCompression; c != nil {
+		if err := decompressRequest(r, c, limit); err != nil {
+			failure(r.Context(), w, err)
+			return
+		}
+		// The response is compressed as a whole, not the calls
+		w = newCompressWriter(w, r, c)
+		r.Header.Del("Accept-Encoding")
+		if cw, ok := w.(*compressWriter); ok {
+			defer cw.close()
+		}
+	}
+
+	body, err := ioutil.ReadAll(r.Body)
+	if err != nil {
+		if _, ok := UnwrapErrorStatusCode(err); ok {
+			failure(r.Context(), w, err)
+			return
+		}
+		writeRPC(w, rpcError(nullID, JSONRPCParseError, "Parse error"))
+		return
+	}
+
+	body = bytes.TrimSpace(body)
+	if !json.Valid(body) {
+		writeRPC(w, rpcError(nullID, JSONRPCParseError, "Parse error"))
+		return
+	}
+
+	// Single request
+	if len(body) == 0 || body[0] != '[' {
+		if resp := j.call(w.Header(), r, body); resp != nil {
+			writeRPC(w, resp)
+		} else {
+			w.WriteHeader(http.StatusNoContent)
+		}
+		return
+	}
+
+	// Batch request
+	var batch []json.RawMessage
+	if err := json.Unmarshal(body, &batch); err != nil || len(batch) == 0 {
+		writeRPC(w, rpcError(nullID, JSONRPCInvalidRequest, "Invalid Request"))
+		return
+	}
+	responses := make([]*rpcResponse, 0, len(batch))
+	for _, raw := range batch {
+		if resp := j.call(w.Header(), r, raw); resp != nil {
+			responses = append(responses, resp)
+		}
+	}
+	if len(responses) == 0 {
+		w.WriteHeader(http.StatusNoContent)
+		return
+	}
+	writeRPC(w, responses)
+}
+
+// call handles a single request and returns nil for notifications, header is
+// the header of the whole response
+func (j *JSONRPC) call(header http.Header, r *http.Request, raw json.RawMessage) *rpcResponse {
+	req, ok := parseRPCRequest(raw)
+	if !ok {
+		return rpcError(req.id, JSONRPCInvalidRequest, "Invalid Request")
+	}
+
+	j.mu.RLock()
+	m := j.methods[req.method]
+	j.mu.RUnlock()
+	if m == nil {
+		return notify(req, rpcError(req.id, JSONRPCMethodNotFound, "Method not found"))
+	}
+
+	params := req.params
+	if len(params) > 0 && params[0] == '[' && !m.positional {
+		var ok bool
+		if params, ok = m.named(params); !ok {
+			return notify(req, rpcError(req.id, JSONRPCInvalidParams, "Invalid params"))
+		}
+	}
+
+	// Each call is served as a request whose body is the params, so that
+	// the plugins and the decoding are the same as the HTTP endpoints
+	sub := r.WithContext(r.Context())
+	sub.Body = ioutil.NopCloser(bytes.NewReader(params))
+	sub.ContentLength = int64(len(params))
+
+	ctx := context.WithValue(sub.Context(), responseHeaderKey{}, header)
+	d, status := effectiveTimeout(m.handler.timeout, sub)
+	if d > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, d)
+		defer cancel()
+	}
+	if l := m.handler.concurrency; l != nil {
+		if err := l.acquire(ctx, header); err != nil {
+			return notify(req, handlerError(ctx, req.id, err))
+		}
+		start := time.Now()
+		defer func() { l.release(time.Since(start)) }()
+	}
+
+	ctx, err := m.handler.runPlugins(ctx, sub)
+	if err != nil {
+		return notify(req, handlerError(ctx, req.id, err))
+	}
+	args, err := m.handler.adapter.args(ctx, sub, effectiveDecodeOption(m.handler.decodeOptions))
+	if err != nil {
+		resp := rpcError(req.id, JSONRPCInvalidParams, "Invalid params")
+		resp.Error.Data = err.Error()
+		return notify(req, resp)
+	}
+	payload, _, err := m.handler.adapter.call(args)
+	if err != nil {
+		if ctx.Err() == context.DeadlineExceeded {
+			err = ErrorWithStatusCode(ctx.Err(), status)
+		}
+		return notify(req, handlerError(ctx, req.id, err))
+	}
+
+	var result interface{} = json.RawMessage("null")
+	if !isEmptyPayload(payload) {
+		result = responseEncoder(ctx, payload)
+	}
+	return notify(req, &rpcResponse{Version: "2.0", Result: result, ID: req.id})
+}
+
+// named converts the positional params to the named ones according to the
+// fields, or to the only element if the body is not a struct
+func (m *rpcMethod) named(params json.RawMessage) (json.RawMessage, bool) {
+	var positional []json.RawMessage
+	if err := json.Unmarshal(params, &positional); err != nil {
+		return nil, false
+	}
+	if m.fields == nil {
+		switch len(positional) {
+		case 0:
+			return nil, true
+		case 1:
+			return positional[0], true
+		}
+		return nil, false
+	}
+
+	if len(positional) > len(m.fields) {
+		return nil, false
+	}
+	named := make(map[string]json.RawMessage, len(positional))
+	for i, v := range positional {
+		named[m.fields[i]] = v
+	}
+	data, err := json.Marshal(named)
+	return data, err == nil
+}
+
+// jsonFields returns the JSON names of the fields of struct in declaration
+// order, the fields of embedded structs are flattened as encoding/json does
+func jsonFields(t reflect.Type) []string {
+	fields := make([]string, 0, t.NumField())
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		name := strings.Split(f.Tag.Get("json"), ",")[0]
+		if name == "-" {
+			continue
+		}
+		if f.Anonymous && name == "" && indirectType(f.Type).Kind() == reflect.Struct {
+			fields = append(fields, jsonFields(indirectType(f.Type))...)
+			continue
+		}
+		if f.PkgPath != "" {
+			continue
+		}
+		if name == "" {
+			name = f.Name
+		}
+		fields = append(fields, name)
+	}
+	return fields
+}
+
+// bodyType returns the type which the body is decoded into, nil if the
+// handler does not consume the body
+func bodyType(a adapter) reflect.Type {
+	switch a := a.(type) {
+	case *simpleUnaryAdapter:
+		return a.argType
+	case *genericAdapter:
+		for i, typ := range a.types {
+			if a.sources[i] == sourceBinder && consumesBody(typ) {
+				return typ.Field(0).Type
+			}
+			if a.sources[i] == sourceBody {
+				return typ
+			}
+		}
+	}
+	return nil
+}
+
+// parseRPCRequest parses the request object, the id is null if the request
+// is invalid
+func parseRPCRequest(raw json.RawMessage) (rpcRequest, bool) {
+	req := rpcRequest{id: nullID}
+	var fields map[string]json.RawMessage
+	if err := json.Unmarshal(raw, &fields); err != nil || fields == nil {
+		return req, false
+	}
+
+	var version string
+	if err := json.Unmarshal(fields["jsonrpc"], &version); err != nil || version != "2.0" {
+		return req, false
+	}
+	if method := fields["method"]; len(method) == 0 || method[0] != '"' || json.Unmarshal(method, &req.method) != nil {
+		return req, false
+	}
+
+	if id, ok := fields["id"]; ok {
+		switch id[0] {
+		case '"', 'n', '-', '0', '1', '2', '3', '4', '5', '6', '7', '8', '9':
+			req.id = id
+		default:
+			return req, false
+		}
+	} else {
+		req.id = nil
+	}
+
+	if params, ok := fields["params"]; ok {
+		if params[0] != '{' && params[0] != '[' {
+			return req, false
+		}
+		req.params = params
+	}
+	return req, true
+}
+
+// handlerError converts the error of plugins and handlers to the error
+// object, the status code (if any) is used as the error code and the output
+// of error encoder is attached as the data
+func handlerError(ctx context.Context, id json.RawMessage, err error) *rpcResponse {
+	if e, ok := err.(*JSONRPCError); ok {
+		return &rpcResponse{Version: "2.0", Error: e, ID: id}
+	}
+	resp := rpcError(id, JSONRPCServerError, err.Error())
+	if code, ok := UnwrapErrorStatusCode(err); ok {
+		resp.Error.Code = code
+	}
+	resp.Error.Data = errorEncoder(ctx, err)
+	return resp
+}
+
+func rpcError(id json.RawMessage, code int, message string) *rpcResponse {
+	return &rpcResponse{
+		Version: "2.0",
+		Error:   &JSONRPCError{Code: code, Message: message},
+		ID:      id,
+	}
+}
+
+// notify drops the response of notifications
+func notify(req rpcRequest, resp *rpcResponse) *rpcResponse {
+	if req.id == nil {
+		return nil
+	}
+	return resp
+}
+
+func writeRPC(w http.ResponseWriter, v interface{}) {
+	_ = json.NewEncoder(w).Encode(v)
+}
@@ -0,0 +1,196 @@
+// Copyright 2026 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package fn
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"errors"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestJSONRPC(t *testing.T) {
+	SetErrorEncoder(func(ctx context.Context, err error) interface{} {
+		return err.Error()
+	})
+	SetResponseEncoder(func(ctx context.Context, payload interface{}) interface{} {
+		return payload
+	})
+
+	var notified bool
+	group := NewGroup().Plugin(func(ctx context.Context, r *http.Request) (context.Context, error) {
+		if r.Header.Get("X-Auth-Token") != "valid" {
+			ResponseHeader(ctx).Set("WWW-Authenticate", `Bearer realm="rpc"`)
+			return ctx, ErrorWithStatusCode(errors.New("permission denied"), http.StatusForbidden)
+		}
+		return ctx, nil
+	})
+
+	rpc := NewJSONRPC().
+		Register("echo", func(ctx context.Context, req *testRequest) (*testResponse, error) {
+			return &testResponse{Code: req.Bar, Message: req.Foo}, nil
+		}).
+		Register("sum", func(nums []int) (int, error) {
+			sum := 0
+			for _, n := range nums {
+				sum += n
+			}
+			return sum, nil
+		}).
+		Register("notify", func() error {
+			notified = true
+			return nil
+		}).
+		Register("fail", func() (*testResponse, error) {
+			return nil, ErrorWithStatusCode(errors.New("not found"), http.StatusNotFound)
+		}).
+		Register("secret", group.Wrap(func() (string, error) { return "secret", nil })).
+		Register("encoding", func(header http.Header) (string, error) {
+			return header.Get("Accept-Encoding") + header.Get("Content-Encoding"), nil
+		})
+
+	cases := []struct {
+		body   string
+		expect string
+	}{
+		// named and positional params
+		{`{"jsonrpc":"2.0","method":"echo","params":{"foo":"a","bar":1},"id":1}`,
+			`{"jsonrpc":"2.0","result":{"code":1,"message":"a"},"id":1}`},
+		{`{"jsonrpc":"2.0","method":"echo","params":["b"],"id":"x"}`,
+			`{"jsonrpc":"2.0","result":{"code":0,"message":"b"},"id":"x"}`},
+		{`{"jsonrpc":"2.0","method":"echo","params":["x",1],"id":"y"}`,
+			`{"jsonrpc":"2.0","result":{"code":1,"message":"x"},"id":"y"}`},
+		{`{"jsonrpc":"2.0","method":"sum","params":[1,2,3],"id":null}`,
+			`{"jsonrpc":"2.0","result":6,"id":null}`},
+		{`{"jsonrpc":"2.0","method":"notify","id":2}`,
+			`{"jsonrpc":"2.0","result":null,"id":2}`},
+		// errors
+		{`{"jsonrpc":"2.0","method":"echo","params":{"foo":`,
+			`{"jsonrpc":"2.0","error":{"code":-32700,"message":"Parse error"},"id":null}`},
+		{`{"jsonrpc":"1.0","method":"echo","id":3}`,
+			`{"jsonrpc":"2.0","error":{"code":-32600,"message":"Invalid Request"},"id":null}`},
+		{`{"jsonrpc":"2.0","method":1,"id":3}`,
+			`{"jsonrpc":"2.0","error":{"code":-32600,"message":"Invalid Request"},"id":null}`},
+		{`[]`,
+			`{"jsonrpc":"2.0","error":{"code":-32600,"message":"Invalid Request"},"id":null}`},
+		{`{"jsonrpc":"2.0","method":"missing","id":4}`,
+			`{"jsonrpc":"2.0","error":{"code":-32601,"message":"Method not found"},"id":4}`},
+		{`{"jsonrpc":"2.0","method":"echo","params":["c",1,{}],"id":5}`,
+			`{"jsonrpc":"2.0","error":{"code":-32602,"message":"Invalid params"},"id":5}`},
+		{`{"jsonrpc":"2.0","method":"echo","params":{"bar":"x"},"id":6}`,
+			`{"jsonrpc":"2.0","error":{"code":-32602,"message":"Invalid params","data":"json: cannot unmarshal string into Go struct field testRequest.bar of type int"},"id":6}`},
+		{`{"jsonrpc":"2.0","method":"fail","id":7}`,
+			`{"jsonrpc":"2.0","error":{"code":404,"message":"not found","data":"not found"},"id":7}`},
+		{`{"jsonrpc":"2.0","method":"secret","id":8}`,
+			`{"jsonrpc":"2.0","error":{"code":403,"message":"permission denied","data":"permission denied"},"id":8}`},
+		// batch
+		{`[{"jsonrpc":"2.0","method":"sum","params":[1,2],"id":1},{"jsonrpc":"2.0","method":"notify"},1]`,
+			`[{"jsonrpc":"2.0","result":3,"id":1},{"jsonrpc":"2.0","error":{"code":-32600,"message":"Invalid Request"},"id":null}]`},
+	}
+
+	for _, c := range cases {
+		recorder := httptest.NewRecorder()
+		rpc.ServeHTTP(recorder, httptest.NewRequest(http.MethodPost, "/rpc", strings.NewReader(c.body)))
+		require.Equal(t, http.StatusOK, recorder.Code, c.body)
+		require.JSONEq(t, c.expect, recorder.Body.String(), c.body)
+	}
+
+	// notifications are not responded
+	for _, body := range []string{
+		`{"jsonrpc":"2.0","method":"notify"}`,
+		`[{"jsonrpc":"2.0","method":"notify"},{"jsonrpc":"2.0","method":"missing"}]`,
+	} {
+		notified = false
+		recorder := httptest.NewRecorder()
+		rpc.ServeHTTP(recorder, httptest.NewRequest(http.MethodPost, "/rpc", strings.NewReader(body)))
+		require.Equal(t, http.StatusNoContent, recorder.Code)
+		require.Empty(t, recorder.Body.String())
+		require.True(t, notified)
+	}
+
+	// The headers set by the plugins are sent with the response
+	recorder := httptest.NewRecorder()
+	rpc.ServeHTTP(recorder, httptest.NewRequest(http.MethodPost, "/rpc", strings.NewReader(`{"jsonrpc":"2.0","method":"secret","id":1}`)))
+	require.Equal(t, `Bearer realm="rpc"`, recorder.Header().Get("WWW-Authenticate"))
+
+	recorder = httptest.NewRecorder()
+	request := httptest.NewRequest(http.MethodPost, "/rpc", strings.NewReader(`{"jsonrpc":"2.0","method":"secret","id":1}`))
+	request.Header.Set("X-Auth-Token", "valid")
+	rpc.ServeHTTP(recorder, request)
+	require.JSONEq(t, `{"jsonrpc":"2.0","result":"secret","id":1}`, recorder.Body.String())
+
+	// The compressed request is decompressed, and the response is compressed
+	// as a whole
+	SetCompression(&CompressionOptions{MinSize: 10})
+	defer SetCompression(nil)
+	recorder = httptest.NewRecorder()
+	request = httptest.NewRequest(http.MethodPost, "/rpc", bytes.NewReader(gzipBytes(t, `{"jsonrpc":"2.0","method":"encoding","id":1}`)))
+	request.Header.Set("Content-Encoding", "gzip")
+	request.Header.Set("Accept-Encoding", "gzip")
+	rpc.ServeHTTP(recorder, request)
+	require.Equal(t, http.StatusOK, recorder.Code)
+	require.Equal(t, "gzip", recorder.Header().Get("Content-Encoding"))
+	zr, err := gzip.NewReader(recorder.Body)
+	require.NoError(t, err)
+	body, err := ioutil.ReadAll(zr)
+	require.NoError(t, err)
+	require.JSONEq(t, `{"jsonrpc":"2.0","result":"","id":1}`, string(body))
+}
+
+func TestJSONRPCLimits(t *testing.T) {
+	SetErrorEncoder(func(ctx context.Context, err error) interface{} {
+		return err.Error()
+	})
+
+	release := make(chan struct{})
+	limiter := NewConcurrencyLimiter(1)
+	rpc := NewJSONRPC().
+		Register("wait", Wrap(func(ctx context.Context) error {
+			<-ctx.Done()
+			return ctx.Err()
+		}).Timeout(10*time.Millisecond)).
+		Register("slow", Wrap(func() error {
+			<-release
+			return nil
+		}).Concurrency(limiter))
+	call := func(body string) *httptest.ResponseRecorder {
+		recorder := httptest.NewRecorder()
+		rpc.ServeHTTP(recorder, httptest.NewRequest(http.MethodPost, "/rpc", strings.NewReader(body)))
+		return recorder
+	}
+
+	// The timeout of handler cancels the call
+	recorder := call(`{"jsonrpc":"2.0","method":"wait","id":1}`)
+	require.JSONEq(t, `{"jsonrpc":"2.0","error":{"code":503,"message":"context deadline exceeded","data":"context deadline exceeded"},"id":1}`, recorder.Body.String())
+
+	// The concurrency limit of handler rejects the call
+	done := make(chan *httptest.ResponseRecorder, 1)
+	go func() { done <- call(`{"jsonrpc":"2.0","method":"slow","id":1}`) }()
+	require.Eventually(t, func() bool { return limiter.InFlight() == 1 }, 5*time.Second, time.Millisecond)
+	recorder = call(`{"jsonrpc":"2.0","method":"slow","id":2}`)
+	require.Equal(t, "1", recorder.Header().Get("Retry-After"))
+	require.JSONEq(t, `{"jsonrpc":"2.0","error":{"code":503,"message":"`+ErrConcurrencyLimited.Error()+`","data":"`+ErrConcurrencyLimited.Error()+`"},"id":2}`, recorder.Body.String())
+	close(release)
+	require.JSONEq(t, `{"jsonrpc":"2.0","result":null,"id":1}`, (<-done).Body.String())
+	require.Equal(t, 0, limiter.InFlight())
+}
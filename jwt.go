@@ -0,0 +1,426 @@
+// Copyright 2026 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package fn
+
+import (
+	"bytes"
+	"context"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/hmac"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"math/big"
+	"net/http"
+	"reflect"
+	"strings"
+	"sync"
+	"time"
+)
+
+// The algorithms of JWT signature
+const (
+	HS256 = "HS256"
+	RS256 = "RS256"
+	ES256 = "ES256"
+)
+
+// ErrMissingToken is returned (with status code 401) when the request has
+// no bearer token
+var ErrMissingToken = errors.New("missing bearer token")
+
+type (
+	// TokenError is returned (with status code 401) when the bearer token is
+	// invalid
+	TokenError struct {
+		Reason string
+	}
+
+	// JWTKeys is a set of keys verifying the tokens, the keys are identified
+	// by the kid header of tokens and can be rotated at runtime
+	JWTKeys struct {
+		mu   sync.RWMutex
+		keys map[string]jwtKey
+	}
+
+	jwtKey struct {
+		alg string
+		key interface{}
+	}
+
+	// JWTClaims is the claims of a verified token, it can be accepted as a
+	// parameter of the wrapped function
+	//
+	//	func(claims *fn.JWTClaims, req *Request) (*Response, error)
+	JWTClaims struct {
+		Issuer    string
+		Subject   string
+		Audience  []string
+		ExpiresAt time.Time
+		NotBefore time.Time
+		IssuedAt  time.Time
+		ID        string
+
+		raw json.RawMessage
+	}
+
+	// JWTVerifier verifies the bearer token of requests as a plugin
+	JWTVerifier struct {
+		keys     *JWTKeys
+		issuer   string
+		audience string
+		skew     time.Duration
+		realm    string
+		now      func() time.Time
+	}
+
+	jwtHeader struct {
+		Alg string `json:"alg"`
+		Kid string `json:"kid"`
+	}
+
+	jwtRegisteredClaims struct {
+		Issuer    string          `json:"iss"`
+		Subject   string          `json:"sub"`
+		Audience  json.RawMessage `json:"aud"`
+		ExpiresAt *json.Number    `json:"exp"`
+		NotBefore *json.Number    `json:"nbf"`
+		IssuedAt  *json.Number    `json:"iat"`
+		ID        string          `json:"jti"`
+	}
+
+	jwtClaimsKey struct{}
+)
+
+func init() {
+	contextTypes[reflect.TypeOf((*JWTClaims)(nil))] = func(ctx context.Context) (reflect.Value, error) {
+		claims, ok := JWTClaimsFromContext(ctx)
+		if !ok {
+			return reflect.Value{}, ErrorWithStatusCode(ErrMissingToken, http.StatusUnauthorized)
+		}
+		return reflect.ValueOf(claims), nil
+	}
+}
+
+func (e *TokenError) Error() string {
+	return "invalid token: " + e.Reason
+}
+
+func tokenError(reason string) error {
+	return &TokenError{Reason: reason}
+}
+
+// NewJWTKeys returns an empty key set
+func NewJWTKeys() *JWTKeys {
+	return &JWTKeys{keys: map[string]jwtKey{}}
+}
+
+// Add adds (or replaces) the key identified by kid, the key is a []byte for
+// HS256, a *rsa.PublicKey for RS256 or a *ecdsa.PublicKey on P-256 for ES256
+func (k *JWTKeys) Add(kid, alg string, key interface{}) error {
+	switch alg {
+	case HS256:
+		if secret, ok := key.([]byte); !ok || len(secret) == 0 {
+			return fmt.Errorf("fn: %s key should be a non-empty []byte", alg)
+		}
+	case RS256:
+		if _, ok := key.(*rsa.PublicKey); !ok {
+			return fmt.Errorf("fn: %s key should be a *rsa.PublicKey", alg)
+		}
+	case ES256:
+		if pub, ok := key.(*ecdsa.PublicKey); !ok || pub.Curve != elliptic.P256() {
+			return fmt.Errorf("fn: %s key should be a *ecdsa.PublicKey on P-256", alg)
+		}
+	default:
+		return fmt.Errorf("fn: unsupported algorithm %q", alg)
+	}
+	k.mu.Lock()
+	k.keys[kid] = jwtKey{alg: alg, key: key}
+	k.mu.Unlock()
+	return nil
+}
+
+// Remove removes the key identified by kid
+func (k *JWTKeys) Remove(kid string) {
+	k.mu.Lock()
+	delete(k.keys, kid)
+	k.mu.Unlock()
+}
+
+// lookup returns the candidate keys of the token, the keys of the algorithm
+// are tried if the token has no kid
+func (k *JWTKeys) lookup(kid, alg string) []jwtKey {
+	k.mu.RLock()
+	defer k.mu.RUnlock()
+	if kid != "" {
+		if key, ok := k.keys[kid]; ok && key.alg == alg {
+			return []jwtKey{key}
+		}
+		return nil
+	}
+	var keys []jwtKey
+	for _, key := range k.keys {
+		if key.alg == alg {
+			keys = append(keys, key)
+		}
+	}
+	return keys
+}
+
+// JWTClaimsFromContext returns the claims of the token verified by
+// JWTVerifier
+func JWTClaimsFromContext(ctx context.Context) (*JWTClaims, bool) {
+	claims, ok := ctx.Value(jwtClaimsKey{}).(*JWTClaims)
+	return claims, ok
+}
+
+// Decode decodes the claims into v, e.g. a struct of the private claims
+func (c *JWTClaims) Decode(v interface{}) error {
+	return json.Unmarshal(c.raw, v)
+}
+
+// NewJWTVerifier returns a verifier of the tokens signed by the keys.
+//
+//	keys := fn.NewJWTKeys()
+//	keys.Add("2026-01", fn.RS256, publicKey)
+//	verifier := fn.NewJWTVerifier(keys).Issuer("https://auth.example.com").Audience("orders")
+//	group := fn.NewGroup().Plugin(verifier.Plugin)
+func NewJWTVerifier(keys *JWTKeys) *JWTVerifier {
+	return &JWTVerifier{
+		keys: keys,
+		now:  time.Now,
+	}
+}
+
+// Issuer requires the iss claim to be iss
+func (v *JWTVerifier) Issuer(iss string) *JWTVerifier {
+	v.issuer = iss
+	return v
+}
+
+// Audience requires the aud claim to contain aud
+func (v *JWTVerifier) Audience(aud string) *JWTVerifier {
+	v.audience = aud
+	return v
+}
+
+// ClockSkew tolerates the clock difference when checking exp and nbf
+func (v *JWTVerifier) ClockSkew(d time.Duration) *JWTVerifier {
+	v.skew = d
+	return v
+}
+
+// Realm sets the realm of the WWW-Authenticate header
+func (v *JWTVerifier) Realm(realm string) *JWTVerifier {
+	v.realm = realm
+	return v
+}
+
+// Plugin is the PluginFunc verifying the bearer token of the Authorization
+// header, the claims are stored in the context (see JWTClaimsFromContext)
+// with the subject as the principal (see PrincipalFromContext). The
+// requests with missing or invalid tokens are rejected with 401 and the
+// WWW-Authenticate header.
+func (v *JWTVerifier) Plugin(ctx context.Context, r *http.Request) (context.Context, error) {
+	token, ok := bearerToken(r)
+	if !ok {
+		ResponseHeader(ctx).Set("WWW-Authenticate", v.challenge(nil))
+		return ctx, ErrorWithStatusCode(ErrMissingToken, http.StatusUnauthorized)
+	}
+	claims, err := v.Verify(token)
+	if err != nil {
+		ResponseHeader(ctx).Set("WWW-Authenticate", v.challenge(err))
+		return ctx, ErrorWithStatusCode(err, http.StatusUnauthorized)
+	}
+	ctx = context.WithValue(ctx, jwtClaimsKey{}, claims)
+	if claims.Subject != "" {
+		ctx = WithPrincipal(ctx, claims.Subject)
+	}
+	return ctx, nil
+}
+
+// challenge returns the WWW-Authenticate header defined by RFC 6750
+func (v *JWTVerifier) challenge(err error) string {
+	var params []string
+	if v.realm != "" {
+		params = append(params, fmt.Sprintf("realm=%q", v.realm))
+	}
+	if te, ok := err.(*TokenError); ok {
+		params = append(params, `error="invalid_token"`, fmt.Sprintf("error_description=%q", te.Reason))
+	}
+	if len(params) == 0 {
+		return "Bearer"
+	}
+	return "Bearer " + strings.Join(params, ", ")
+}
+
+// Verify verifies the signature and the claims of the token
+func (v *JWTVerifier) Verify(token string) (*JWTClaims, error) {
+	parts := strings.Split(token, ".")
+	if len(parts) != 3 {
+		return nil, tokenError("malformed token")
+	}
+	rawHeader, err1 := base64.RawURLEncoding.DecodeString(parts[0])
+	payload, err2 := base64.RawURLEncoding.DecodeString(parts[1])
+	signature, err3 := base64.RawURLEncoding.DecodeString(parts[2])
+	if err1 != nil || err2 != nil || err3 != nil {
+		return nil, tokenError("malformed token")
+	}
+
+	var header jwtHeader
+	if err := json.Unmarshal(rawHeader, &header); err != nil {
+		return nil, tokenError("malformed header")
+	}
+	switch header.Alg {
+	case HS256, RS256, ES256:
+	default:
+		return nil, tokenError("unsupported algorithm")
+	}
+
+	keys := v.keys.lookup(header.Kid, header.Alg)
+	if len(keys) == 0 {
+		return nil, tokenError("unknown key")
+	}
+	signed := []byte(parts[0] + "." + parts[1])
+	verified := false
+	for _, key := range keys {
+		if verifySignature(key, signed, signature) {
+			verified = true
+			break
+		}
+	}
+	if !verified {
+		return nil, tokenError("invalid signature")
+	}
+
+	claims, err := parseClaims(payload)
+	if err != nil {
+		return nil, err
+	}
+	return claims, v.validate(claims)
+}
+
+func (v *JWTVerifier) validate(claims *JWTClaims) error {
+	now := v.now()
+	if !claims.ExpiresAt.IsZero() && !now.Before(claims.ExpiresAt.Add(v.skew)) {
+		return tokenError("token expired")
+	}
+	if !claims.NotBefore.IsZero() && now.Add(v.skew).Before(claims.NotBefore) {
+		return tokenError("token not valid yet")
+	}
+	if v.issuer != "" && claims.Issuer != v.issuer {
+		return tokenError("invalid issuer")
+	}
+	if v.audience != "" {
+		found := false
+		for _, aud := range claims.Audience {
+			if aud == v.audience {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return tokenError("invalid audience")
+		}
+	}
+	return nil
+}
+
+func verifySignature(key jwtKey, signed, signature []byte) bool {
+	digest := sha256.Sum256(signed)
+	switch key.alg {
+	case HS256:
+		mac := hmac.New(sha256.New, key.key.([]byte))
+		mac.Write(signed)
+		return hmac.Equal(mac.Sum(nil), signature)
+	case RS256:
+		return rsa.VerifyPKCS1v15(key.key.(*rsa.PublicKey), crypto.SHA256, digest[:], signature) == nil
+	case ES256:
+		// The signature is the concatenation of R and S
+		if len(signature) != 64 {
+			return false
+		}
+		r := new(big.Int).SetBytes(signature[:32])
+		s := new(big.Int).SetBytes(signature[32:])
+		return ecdsa.Verify(key.key.(*ecdsa.PublicKey), digest[:], r, s)
+	}
+	return false
+}
+
+func parseClaims(payload []byte) (*JWTClaims, error) {
+	var registered jwtRegisteredClaims
+	dec := json.NewDecoder(bytes.NewReader(payload))
+	dec.UseNumber()
+	if err := dec.Decode(&registered); err != nil {
+		return nil, tokenError("malformed claims")
+	}
+
+	claims := &JWTClaims{
+		Issuer:  registered.Issuer,
+		Subject: registered.Subject,
+		ID:      registered.ID,
+		raw:     payload,
+	}
+	var err error
+	if claims.ExpiresAt, err = numericDate(registered.ExpiresAt); err != nil {
+		return nil, err
+	}
+	if claims.NotBefore, err = numericDate(registered.NotBefore); err != nil {
+		return nil, err
+	}
+	if claims.IssuedAt, err = numericDate(registered.IssuedAt); err != nil {
+		return nil, err
+	}
+
+	// The aud claim is either a string or an array of strings
+	if aud := registered.Audience; len(aud) > 0 && string(aud) != "null" {
+		var single string
+		if err := json.Unmarshal(aud, &single); err == nil {
+			claims.Audience = []string{single}
+		} else if err := json.Unmarshal(aud, &claims.Audience); err != nil {
+			return nil, tokenError("malformed aud claim")
+		}
+	}
+	return claims, nil
+}
+
+// numericDate converts the seconds since epoch to time
+func numericDate(n *json.Number) (time.Time, error) {
+	if n == nil {
+		return time.Time{}, nil
+	}
+	f, err := n.Float64()
+	if err != nil || math.IsInf(f, 0) || math.IsNaN(f) {
+		return time.Time{}, tokenError("malformed numeric date")
+	}
+	sec, frac := math.Modf(f)
+	return time.Unix(int64(sec), int64(frac*1e9)), nil
+}
+
+func bearerToken(r *http.Request) (string, bool) {
+	auth := r.Header.Get("Authorization")
+	const prefix = "bearer "
+	if len(auth) <= len(prefix) || !strings.EqualFold(auth[:len(prefix)], prefix) {
+		return "", false
+	}
+	token := strings.TrimSpace(auth[len(prefix):])
+	return token, token != ""
+}
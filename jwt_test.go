@@ -0,0 +1,206 @@
+// Copyright 2026 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package fn
+
+import (
+	"context"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func signJWT(t *testing.T, alg, kid string, key interface{}, claims map[string]interface{}) string {
+	header := map[string]string{"alg": alg, "typ": "JWT"}
+	if kid != "" {
+		header["kid"] = kid
+	}
+	h, err := json.Marshal(header)
+	require.NoError(t, err)
+	c, err := json.Marshal(claims)
+	require.NoError(t, err)
+	signed := base64.RawURLEncoding.EncodeToString(h) + "." + base64.RawURLEncoding.EncodeToString(c)
+
+	digest := sha256.Sum256([]byte(signed))
+	var signature []byte
+	switch alg {
+	case HS256:
+		mac := hmac.New(sha256.New, key.([]byte))
+		mac.Write([]byte(signed))
+		signature = mac.Sum(nil)
+	case RS256:
+		signature, err = rsa.SignPKCS1v15(rand.Reader, key.(*rsa.PrivateKey), crypto.SHA256, digest[:])
+		require.NoError(t, err)
+	case ES256:
+		r, s, err := ecdsa.Sign(rand.Reader, key.(*ecdsa.PrivateKey), digest[:])
+		require.NoError(t, err)
+		signature = make([]byte, 64)
+		r.FillBytes(signature[:32])
+		s.FillBytes(signature[32:])
+	}
+	return signed + "." + base64.RawURLEncoding.EncodeToString(signature)
+}
+
+func TestJWTVerifier(t *testing.T) {
+	rsaKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	require.NoError(t, err)
+	ecKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+	secret := []byte("secret")
+
+	keys := NewJWTKeys()
+	require.NoError(t, keys.Add("hs", HS256, secret))
+	require.NoError(t, keys.Add("rs", RS256, &rsaKey.PublicKey))
+	require.NoError(t, keys.Add("es", ES256, &ecKey.PublicKey))
+	require.Error(t, keys.Add("bad", RS256, secret))
+	require.Error(t, keys.Add("bad", "none", secret))
+
+	now := time.Unix(1700000000, 0)
+	verifier := NewJWTVerifier(keys).Issuer("issuer").Audience("orders").ClockSkew(time.Minute)
+	verifier.now = func() time.Time { return now }
+
+	claims := map[string]interface{}{
+		"iss":   "issuer",
+		"sub":   "alice",
+		"aud":   []string{"orders", "users"},
+		"exp":   now.Add(time.Hour).Unix(),
+		"nbf":   now.Unix(),
+		"iat":   now.Unix(),
+		"scope": "orders:read",
+	}
+	for alg, key := range map[string]interface{}{HS256: secret, RS256: rsaKey, ES256: ecKey} {
+		kid := map[string]string{HS256: "hs", RS256: "rs", ES256: "es"}[alg]
+		verified, err := verifier.Verify(signJWT(t, alg, kid, key, claims))
+		require.NoError(t, err, alg)
+		require.Equal(t, "alice", verified.Subject)
+		require.Equal(t, []string{"orders", "users"}, verified.Audience)
+		require.Equal(t, now.Add(time.Hour), verified.ExpiresAt)
+
+		// Without kid, the keys of the algorithm are tried
+		_, err = verifier.Verify(signJWT(t, alg, "", key, claims))
+		require.NoError(t, err, alg)
+	}
+
+	with := func(key string, value interface{}) map[string]interface{} {
+		c := map[string]interface{}{}
+		for k, v := range claims {
+			c[k] = v
+		}
+		c[key] = value
+		return c
+	}
+	otherKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+	for token, reason := range map[string]string{
+		"abc":                               "malformed token",
+		"eA.e30.e30":                        "malformed header",
+		signJWT(t, "none", "", nil, claims): "unsupported algorithm",
+		signJWT(t, HS256, "unknown", secret, claims):                                 "unknown key",
+		signJWT(t, HS256, "rs", secret, claims):                                      "unknown key",
+		signJWT(t, HS256, "hs", []byte("other"), claims):                             "invalid signature",
+		signJWT(t, ES256, "es", otherKey, claims):                                    "invalid signature",
+		signJWT(t, HS256, "hs", secret, with("exp", now.Add(-2*time.Minute).Unix())): "token expired",
+		signJWT(t, HS256, "hs", secret, with("nbf", now.Add(2*time.Minute).Unix())):  "token not valid yet",
+		signJWT(t, HS256, "hs", secret, with("iss", "other")):                        "invalid issuer",
+		signJWT(t, HS256, "hs", secret, with("aud", "users")):                        "invalid audience",
+		signJWT(t, HS256, "hs", secret, with("exp", "tomorrow")):                     "malformed claims",
+	} {
+		_, err := verifier.Verify(token)
+		require.Equal(t, &TokenError{Reason: reason}, err, reason)
+	}
+
+	// Within the clock skew
+	_, err = verifier.Verify(signJWT(t, HS256, "hs", secret, with("exp", now.Add(-30*time.Second).Unix())))
+	require.NoError(t, err)
+	_, err = verifier.Verify(signJWT(t, HS256, "hs", secret, with("aud", "orders")))
+	require.NoError(t, err)
+	// The token is not accepted on or after the expiration time
+	_, err = verifier.Verify(signJWT(t, HS256, "hs", secret, with("exp", now.Add(-time.Minute).Unix())))
+	require.Equal(t, &TokenError{Reason: "token expired"}, err)
+
+	// Key rotation
+	keys.Remove("hs")
+	_, err = verifier.Verify(signJWT(t, HS256, "hs", secret, claims))
+	require.Equal(t, &TokenError{Reason: "unknown key"}, err)
+}
+
+func TestJWTPlugin(t *testing.T) {
+	SetErrorEncoder(func(ctx context.Context, err error) interface{} {
+		return err.Error()
+	})
+
+	secret := []byte("secret")
+	keys := NewJWTKeys()
+	require.NoError(t, keys.Add("hs", HS256, secret))
+	verifier := NewJWTVerifier(keys).Realm("api")
+
+	type scope struct {
+		Scope string `json:"scope"`
+	}
+	var principal string
+	handler := NewGroup().Plugin(verifier.Plugin).Wrap(func(ctx context.Context, claims *JWTClaims, req *testRequest) (*testResponse, error) {
+		principal, _ = PrincipalFromContext(ctx)
+		var s scope
+		if err := claims.Decode(&s); err != nil {
+			return nil, err
+		}
+		return &testResponse{Message: claims.Subject + " " + s.Scope + " " + req.Foo}, nil
+	})
+
+	serve := func(authorization string) *httptest.ResponseRecorder {
+		request := httptest.NewRequest(http.MethodPost, "/", strings.NewReader(`{"foo":"x"}`))
+		if authorization != "" {
+			request.Header.Set("Authorization", authorization)
+		}
+		recorder := httptest.NewRecorder()
+		handler.ServeHTTP(recorder, request)
+		return recorder
+	}
+
+	token := signJWT(t, HS256, "hs", secret, map[string]interface{}{"sub": "alice", "scope": "orders:read"})
+	recorder := serve("Bearer " + token)
+	require.Equal(t, http.StatusOK, recorder.Code)
+	require.JSONEq(t, `{"code":0,"message":"alice orders:read x"}`, recorder.Body.String())
+	require.Equal(t, "alice", principal)
+	require.Equal(t, http.StatusOK, serve("bearer "+token).Code)
+
+	recorder = serve("")
+	require.Equal(t, http.StatusUnauthorized, recorder.Code)
+	require.Equal(t, `Bearer realm="api"`, recorder.Header().Get("WWW-Authenticate"))
+	require.Equal(t, `"missing bearer token"`+"\n", recorder.Body.String())
+	require.Equal(t, http.StatusUnauthorized, serve("Basic dXNlcjpwYXNz").Code)
+
+	recorder = serve("Bearer " + token + "x")
+	require.Equal(t, http.StatusUnauthorized, recorder.Code)
+	require.Equal(t, `Bearer realm="api", error="invalid_token", error_description="invalid signature"`, recorder.Header().Get("WWW-Authenticate"))
+	require.Equal(t, `"invalid token: invalid signature"`+"\n", recorder.Body.String())
+
+	// The claims are required without the verifier
+	recorder = httptest.NewRecorder()
+	Wrap(func(claims *JWTClaims) error { return nil }).ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, "/", nil))
+	require.Equal(t, http.StatusUnauthorized, recorder.Code)
+}
@@ -0,0 +1,261 @@
+// Copyright 2026 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package fn
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+)
+
+var (
+	// DefaultDurationBuckets are the buckets (in seconds) of latency histograms
+	DefaultDurationBuckets = []float64{.0005, .001, .005, .01, .025, .05, .1, .25, .5, 1, 2.5, 5, 10}
+
+	// DefaultSizeBuckets are the buckets (in bytes) of body size histograms
+	DefaultSizeBuckets = []float64{64, 256, 1024, 4096, 16384, 65536, 262144, 1048576, 4194304}
+)
+
+type (
+	// Metrics records the requests served by the wrapped handlers, labelled
+	// by the handler name (see fn.Name), and exposes them in the Prometheus
+	// text exposition format:
+	//
+	//	fn_requests_total{handler,code}                counter, code is the status class, e.g. 2xx
+	//	fn_requests_in_flight{handler}                 gauge
+	//	fn_request_duration_seconds{handler,phase}     histogram, phase is plugin, decode, handler, encode or total
+	//	fn_request_size_bytes{handler}                 histogram
+	//	fn_response_size_bytes{handler}                histogram
+	Metrics struct {
+		durationBuckets []float64
+		sizeBuckets     []float64
+
+		mu       sync.RWMutex
+		handlers map[string]*handlerMetrics
+	}
+
+	handlerMetrics struct {
+		inFlight int64 // atomic
+
+		mu           sync.Mutex
+		requests     map[string]uint64 // status class -> count
+		durations    [phaseCount + 1]*histogram
+		requestSize  *histogram
+		responseSize *histogram
+	}
+
+	histogram struct {
+		buckets []float64
+		counts  []uint64
+		sum     float64
+		count   uint64
+	}
+)
+
+// NewMetrics returns an empty metrics with the default buckets
+func NewMetrics() *Metrics {
+	return &Metrics{
+		durationBuckets: DefaultDurationBuckets,
+		sizeBuckets:     DefaultSizeBuckets,
+		handlers:        map[string]*handlerMetrics{},
+	}
+}
+
+// SetMetrics enables recording the metrics of all wrapped handlers, nil
+// disables it.
+//
+//	metrics := fn.NewMetrics()
+//	fn.SetMetrics(metrics)
+//	http.Handle("/metrics", metrics)
+func SetMetrics(m *Metrics) {
+	if m == nil {
+		observers[metricsSlot] = nil
+		return
+	}
+	observers[metricsSlot] = m
+}
+
+// Buckets replaces the buckets of latency histograms (in seconds) and body
+// size histograms (in bytes), it should be called before recording
+func (m *Metrics) Buckets(durations, sizes []float64) *Metrics {
+	if len(durations) > 0 {
+		m.durationBuckets = durations
+	}
+	if len(sizes) > 0 {
+		m.sizeBuckets = sizes
+	}
+	return m
+}
+
+func (m *Metrics) handler(name string) *handlerMetrics {
+	m.mu.RLock()
+	h, ok := m.handlers[name]
+	m.mu.RUnlock()
+	if ok {
+		return h
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if h, ok = m.handlers[name]; ok {
+		return h
+	}
+	h = &handlerMetrics{
+		requests:     map[string]uint64{},
+		requestSize:  newHistogram(m.sizeBuckets),
+		responseSize: newHistogram(m.sizeBuckets),
+	}
+	for i := range h.durations {
+		h.durations[i] = newHistogram(m.durationBuckets)
+	}
+	m.handlers[name] = h
+	return h
+}
+
+func (m *Metrics) observe(ctx context.Context, x *exchange) (context.Context, probe) {
+	h := m.handler(x.name)
+	atomic.AddInt64(&h.inFlight, 1)
+	return ctx, h
+}
+
+func (h *handlerMetrics) beginPhase(p phase)          {}
+func (h *handlerMetrics) endPhase(p phase, err error) {}
+
+func (h *handlerMetrics) end(x *exchange) {
+	atomic.AddInt64(&h.inFlight, -1)
+	total := x.elapsed()
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.requests[strconv.Itoa(x.status()/100)+"xx"]++
+	for p, d := range x.phases {
+		if x.ran[p] {
+			h.durations[p].observe(d.Seconds())
+		}
+	}
+	h.durations[phaseCount].observe(total.Seconds())
+	h.requestSize.observe(float64(x.requestSize()))
+	h.responseSize.observe(float64(x.writer.written))
+}
+
+func newHistogram(buckets []float64) *histogram {
+	return &histogram{buckets: buckets, counts: make([]uint64, len(buckets))}
+}
+
+func (h *histogram) observe(v float64) {
+	for i, upper := range h.buckets {
+		if v <= upper {
+			h.counts[i]++
+		}
+	}
+	h.sum += v
+	h.count++
+}
+
+func (h *histogram) write(w *bufio.Writer, name, labels string) {
+	for i, upper := range h.buckets {
+		fmt.Fprintf(w, "%s_bucket{%sle=\"%s\"} %d\n", name, labels, formatFloat(upper), h.counts[i])
+	}
+	fmt.Fprintf(w, "%s_bucket{%sle=\"+Inf\"} %d\n", name, labels, h.count)
+	labels = strings.TrimSuffix(labels, ",")
+	fmt.Fprintf(w, "%s_sum{%s} %s\n", name, labels, formatFloat(h.sum))
+	fmt.Fprintf(w, "%s_count{%s} %d\n", name, labels, h.count)
+}
+
+// ServeHTTP exposes the metrics in the Prometheus text exposition format
+func (m *Metrics) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "text/plain; version=0.0.4; charset=utf-8")
+	bw := bufio.NewWriter(w)
+	defer bw.Flush()
+
+	m.mu.RLock()
+	names := make([]string, 0, len(m.handlers))
+	for name := range m.handlers {
+		names = append(names, name)
+	}
+	handlers := make([]*handlerMetrics, len(names))
+	sort.Strings(names)
+	for i, name := range names {
+		handlers[i] = m.handlers[name]
+	}
+	m.mu.RUnlock()
+
+	bw.WriteString("# HELP fn_requests_total Total number of requests by status class.\n")
+	bw.WriteString("# TYPE fn_requests_total counter\n")
+	for i, h := range handlers {
+		h.mu.Lock()
+		classes := make([]string, 0, len(h.requests))
+		for class := range h.requests {
+			classes = append(classes, class)
+		}
+		sort.Strings(classes)
+		for _, class := range classes {
+			fmt.Fprintf(bw, "fn_requests_total{handler=\"%s\",code=\"%s\"} %d\n", escapeLabel(names[i]), class, h.requests[class])
+		}
+		h.mu.Unlock()
+	}
+
+	bw.WriteString("# HELP fn_requests_in_flight Number of requests being served.\n")
+	bw.WriteString("# TYPE fn_requests_in_flight gauge\n")
+	for i, h := range handlers {
+		fmt.Fprintf(bw, "fn_requests_in_flight{handler=\"%s\"} %d\n", escapeLabel(names[i]), atomic.LoadInt64(&h.inFlight))
+	}
+
+	bw.WriteString("# HELP fn_request_duration_seconds Latency of requests by phase.\n")
+	bw.WriteString("# TYPE fn_request_duration_seconds histogram\n")
+	for i, h := range handlers {
+		h.mu.Lock()
+		for p, hist := range h.durations {
+			name := "total"
+			if phase(p) < phaseCount {
+				name = phase(p).String()
+			}
+			hist.write(bw, "fn_request_duration_seconds", fmt.Sprintf("handler=\"%s\",phase=\"%s\",", escapeLabel(names[i]), name))
+		}
+		h.mu.Unlock()
+	}
+
+	bw.WriteString("# HELP fn_request_size_bytes Size of request bodies.\n")
+	bw.WriteString("# TYPE fn_request_size_bytes histogram\n")
+	for i, h := range handlers {
+		h.mu.Lock()
+		h.requestSize.write(bw, "fn_request_size_bytes", fmt.Sprintf("handler=\"%s\",", escapeLabel(names[i])))
+		h.mu.Unlock()
+	}
+
+	bw.WriteString("# HELP fn_response_size_bytes Size of response bodies.\n")
+	bw.WriteString("# TYPE fn_response_size_bytes histogram\n")
+	for i, h := range handlers {
+		h.mu.Lock()
+		h.responseSize.write(bw, "fn_response_size_bytes", fmt.Sprintf("handler=\"%s\",", escapeLabel(names[i])))
+		h.mu.Unlock()
+	}
+}
+
+var labelEscaper = strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`)
+
+func escapeLabel(v string) string {
+	return labelEscaper.Replace(v)
+}
+
+func formatFloat(v float64) string {
+	return strconv.FormatFloat(v, 'g', -1, 64)
+}
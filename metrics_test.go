@@ -0,0 +1,70 @@
+// Copyright 2026 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package fn
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestMetrics(t *testing.T) {
+	metrics := NewMetrics().Buckets([]float64{1}, []float64{10, 100})
+	SetMetrics(metrics)
+	defer SetMetrics(nil)
+
+	ok := Wrap(func(ctx context.Context, req *testRequest) (*testResponse, error) {
+		return &testResponse{Message: req.Foo}, nil
+	}).Name(`echo "v1"`)
+	fail := Wrap(func() error {
+		return ErrorWithStatusCode(errors.New("internal"), http.StatusInternalServerError)
+	})
+
+	for i := 0; i < 2; i++ {
+		ok.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodPost, "/", strings.NewReader(`{"foo":"hello"}`)))
+	}
+	ok.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodPost, "/", strings.NewReader(`{`)))
+	fail.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/", nil))
+
+	recorder := httptest.NewRecorder()
+	metrics.ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+	require.Equal(t, "text/plain; version=0.0.4; charset=utf-8", recorder.Header().Get("Content-Type"))
+	output := recorder.Body.String()
+
+	for _, line := range []string{
+		"# TYPE fn_requests_total counter",
+		`fn_requests_total{handler="echo \"v1\"",code="2xx"} 2`,
+		`fn_requests_total{handler="echo \"v1\"",code="4xx"} 1`,
+		`fn_requests_total{handler="fn.TestMetrics.func2",code="5xx"} 1`,
+		`fn_requests_in_flight{handler="echo \"v1\""} 0`,
+		"# TYPE fn_request_duration_seconds histogram",
+		`fn_request_duration_seconds_bucket{handler="echo \"v1\"",phase="handler",le="+Inf"} 2`,
+		`fn_request_duration_seconds_count{handler="echo \"v1\"",phase="decode"} 3`,
+		`fn_request_duration_seconds_count{handler="echo \"v1\"",phase="encode"} 3`,
+		`fn_request_duration_seconds_count{handler="echo \"v1\"",phase="total"} 3`,
+		`fn_request_duration_seconds_count{handler="fn.TestMetrics.func2",phase="decode"} 1`,
+		`fn_request_size_bytes_bucket{handler="echo \"v1\"",le="10"} 1`,
+		`fn_request_size_bytes_bucket{handler="echo \"v1\"",le="100"} 3`,
+		`fn_request_size_bytes_sum{handler="echo \"v1\""} 31`,
+		`fn_response_size_bytes_count{handler="fn.TestMetrics.func2"} 1`,
+	} {
+		require.Contains(t, output, line+"\n")
+	}
+}
@@ -0,0 +1,289 @@
+// Copyright 2026 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package fn
+
+import (
+	"context"
+	"io"
+	"net"
+	"net/http"
+	"reflect"
+	"runtime"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// phase represents a stage of serving a request
+type phase int
+
+const (
+	phasePlugin  phase = iota // global and handler plugins
+	phaseDecode               // extracting the arguments from the request
+	phaseHandler              // calling the wrapped function
+	phaseEncode               // encoding the response
+	phaseCount
+)
+
+var phaseNames = [phaseCount]string{"plugin", "decode", "handler", "encode"}
+
+func (p phase) String() string {
+	return phaseNames[p]
+}
+
+type (
+	// observer observes the requests served by the wrapped handlers, e.g.
+	// metrics, tracing and access log
+	observer interface {
+		// observe is called when a request arrives, the returned probe is
+		// notified about the phases and the end of the request
+		observe(ctx context.Context, x *exchange) (context.Context, probe)
+	}
+
+	probe interface {
+		beginPhase(p phase)
+		endPhase(p phase, err error)
+		end(x *exchange)
+	}
+
+	// exchange represents a request served by a handler and its response
+	exchange struct {
+		name       string
+		request    *http.Request
+		writer     *responseWriter
+		body       *countingBody
+		start      time.Time
+		phaseStart time.Time
+		phases     [phaseCount]time.Duration
+		ran        [phaseCount]bool
+		err        error
+		probes     []probe
+
+		// The phases may be run by another goroutine if the handler has a
+		// timeout, which is detached once the timeout response is sent
+		mu       sync.Mutex
+		active   bool
+		current  phase
+		detached bool
+	}
+
+	// responseWriter records the status code and the size of response
+	responseWriter struct {
+		http.ResponseWriter
+		status  int
+		written int64
+	}
+
+	// countingBody counts the bytes read from the request body
+	countingBody struct {
+		io.ReadCloser
+		read int64 // atomic
+	}
+)
+
+// The observers configured globally, each slot is set by the corresponding
+// Set* function
+const (
+	requestIDSlot = iota
+	metricsSlot
+	tracingSlot
+	accessLogSlot
+	observerSlots
+)
+
+var observers [observerSlots]observer
+
+// newExchange starts observing the request
+func newExchange(ctx context.Context, name string, w http.ResponseWriter, r *http.Request) (context.Context, *exchange) {
+	x := &exchange{
+		name:    name,
+		request: r,
+		writer:  &responseWriter{ResponseWriter: w},
+		start:   time.Now(),
+	}
+	if r.Body != nil && r.Body != http.NoBody {
+		x.body = &countingBody{ReadCloser: r.Body}
+		r.Body = x.body
+	}
+	for _, o := range observers {
+		if o == nil {
+			continue
+		}
+		var p probe
+		ctx, p = o.observe(ctx, x)
+		if p != nil {
+			x.probes = append(x.probes, p)
+		}
+	}
+	return ctx, x
+}
+
+func (x *exchange) beginPhase(p phase) {
+	x.mu.Lock()
+	defer x.mu.Unlock()
+	if !x.detached {
+		x.begin(p)
+	}
+}
+
+func (x *exchange) endPhase(p phase, err error) {
+	x.mu.Lock()
+	defer x.mu.Unlock()
+	if !x.detached {
+		x.finish(p, err)
+	}
+}
+
+func (x *exchange) begin(p phase) {
+	x.phaseStart = time.Now()
+	x.active, x.current = true, p
+	for _, probe := range x.probes {
+		probe.beginPhase(p)
+	}
+}
+
+func (x *exchange) finish(p phase, err error) {
+	x.phases[p] = time.Since(x.phaseStart)
+	x.ran[p] = true
+	x.active = false
+	for _, probe := range x.probes {
+		probe.endPhase(p, err)
+	}
+}
+
+// failure encodes the error as the response
+func (x *exchange) failure(ctx context.Context, w http.ResponseWriter, err error) {
+	x.mu.Lock()
+	defer x.mu.Unlock()
+	if !x.detached {
+		x.err = err
+		x.encodeFailure(ctx, w, err)
+	}
+}
+
+// abort detaches the goroutine running the phases and encodes the error as
+// the response, nothing is written if w is nil
+func (x *exchange) abort(ctx context.Context, w http.ResponseWriter, err error) {
+	x.mu.Lock()
+	defer x.mu.Unlock()
+	x.detached = true
+	if x.active {
+		x.finish(x.current, err)
+	}
+	x.err = err
+	if w != nil {
+		x.encodeFailure(ctx, w, err)
+	}
+}
+
+func (x *exchange) encodeFailure(ctx context.Context, w http.ResponseWriter, err error) {
+	x.begin(phaseEncode)
+	failure(ctx, w, err)
+	x.finish(phaseEncode, nil)
+}
+
+func (x *exchange) end() {
+	x.mu.Lock()
+	defer x.mu.Unlock()
+	for _, probe := range x.probes {
+		probe.end(x)
+	}
+}
+
+// elapsed returns the duration since the request arrived
+func (x *exchange) elapsed() time.Duration {
+	return time.Since(x.start)
+}
+
+// status returns the status code of response
+func (x *exchange) status() int {
+	if x.writer.status == 0 {
+		return http.StatusOK
+	}
+	return x.writer.status
+}
+
+// requestSize returns the size of request body, which is the larger one of
+// the Content-Length and the bytes read
+func (x *exchange) requestSize() int64 {
+	size := x.request.ContentLength
+	if x.body != nil {
+		if read := atomic.LoadInt64(&x.body.read); read > size {
+			size = read
+		}
+	}
+	if size < 0 {
+		return 0
+	}
+	return size
+}
+
+func (w *responseWriter) WriteHeader(statusCode int) {
+	if w.status == 0 {
+		w.status = statusCode
+	}
+	w.ResponseWriter.WriteHeader(statusCode)
+}
+
+func (w *responseWriter) Write(p []byte) (int, error) {
+	if w.status == 0 {
+		w.status = http.StatusOK
+	}
+	n, err := w.ResponseWriter.Write(p)
+	w.written += int64(n)
+	return n, err
+}
+
+func (b *countingBody) Read(p []byte) (int, error) {
+	n, err := b.ReadCloser.Read(p)
+	atomic.AddInt64(&b.read, int64(n))
+	return n, err
+}
+
+// funcName returns the name of the wrapped function, which is the default
+// name of handler
+func funcName(f interface{}) string {
+	name := runtime.FuncForPC(reflect.ValueOf(f).Pointer()).Name()
+	name = strings.TrimSuffix(name, "-fm")
+	if i := strings.LastIndex(name, "/"); i >= 0 {
+		name = name[i+1:]
+	}
+	return name
+}
+
+// clientIP returns the IP address of the client, the first address of the
+// X-Forwarded-For header (or the X-Real-IP header) is preferred if the proxy
+// in front of the server is trusted
+func clientIP(r *http.Request, trustProxy bool) string {
+	if trustProxy {
+		if forwarded := r.Header.Get("X-Forwarded-For"); forwarded != "" {
+			if i := strings.IndexByte(forwarded, ','); i >= 0 {
+				forwarded = forwarded[:i]
+			}
+			if ip := strings.TrimSpace(forwarded); ip != "" {
+				return ip
+			}
+		}
+		if ip := strings.TrimSpace(r.Header.Get("X-Real-IP")); ip != "" {
+			return ip
+		}
+	}
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		return r.RemoteAddr
+	}
+	return host
+}
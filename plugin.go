@@ -31,3 +31,33 @@ func Plugin(plugins ...PluginFunc) {
 		}
 	}
 }
+
+type (
+	principalKey      struct{}
+	responseHeaderKey struct{}
+)
+
+// WithPrincipal returns a copy of ctx carrying the authenticated principal
+// (e.g. the user id), which is set by the authentication plugins and used
+// by the rate limiting and idempotency as the key.
+func WithPrincipal(ctx context.Context, principal string) context.Context {
+	return context.WithValue(ctx, principalKey{}, principal)
+}
+
+// PrincipalFromContext returns the authenticated principal set by
+// WithPrincipal
+func PrincipalFromContext(ctx context.Context) (string, bool) {
+	principal, ok := ctx.Value(principalKey{}).(string)
+	return principal, ok && principal != ""
+}
+
+// ResponseHeader returns the header of response in the plugins and the
+// wrapped functions, which is sent with both the successful and the error
+// responses, e.g. the rate limit headers.
+func ResponseHeader(ctx context.Context) http.Header {
+	if header, ok := ctx.Value(responseHeaderKey{}).(http.Header); ok {
+		return header
+	}
+	// Not served by a wrapped handler, e.g. a JSON-RPC call
+	return http.Header{}
+}
@@ -0,0 +1,326 @@
+// Copyright 2026 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package fn
+
+import (
+	"context"
+	"errors"
+	"math"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// ErrRateLimited is returned (with status code 429) when the request
+// exceeds the rate limit
+var ErrRateLimited = errors.New("rate limit exceeded")
+
+// RateLimitAlgorithm is the algorithm of rate limiting
+type RateLimitAlgorithm int
+
+const (
+	// TokenBucket allows bursts up to the limit, and refills the bucket at
+	// the rate of limit per window
+	TokenBucket RateLimitAlgorithm = iota
+	// SlidingWindow allows the limit of requests in any window, which is
+	// estimated by weighting the count of the previous fixed window
+	SlidingWindow
+)
+
+const memoryStoreSweepInterval = time.Minute
+
+type (
+	// RateLimit is the policy of rate limiting, e.g. 100 requests per minute
+	RateLimit struct {
+		Algorithm RateLimitAlgorithm
+		Limit     int
+		Window    time.Duration
+	}
+
+	// RateLimitResult is the result of taking a request from the quota
+	RateLimitResult struct {
+		Allowed   bool
+		Remaining int
+		// Reset is the duration until the quota is fully restored
+		Reset time.Duration
+		// RetryAfter is the duration until the next request is allowed,
+		// which is zero if the request is allowed
+		RetryAfter time.Duration
+	}
+
+	// RateLimitStore stores the state of rate limiting, the in-memory store
+	// is used by default, a shared store (e.g. Redis) is required to limit
+	// the requests across instances. A store can be shared by the limiters,
+	// so the states should be separated by the limit as well as the key.
+	RateLimitStore interface {
+		// Take takes a request of the key from the quota of limit atomically
+		Take(ctx context.Context, key string, limit RateLimit) (RateLimitResult, error)
+	}
+
+	// RateLimitKeyFunc returns the key of the request which the quota
+	// belongs to, the request is not limited if the key is empty
+	RateLimitKeyFunc func(ctx context.Context, r *http.Request) string
+
+	// RateLimiter limits the rate of requests as a plugin
+	RateLimiter struct {
+		limit RateLimit
+		key   RateLimitKeyFunc
+		store RateLimitStore
+	}
+
+	// MemoryRateLimitStore is a RateLimitStore in memory, the idle states
+	// are evicted periodically
+	MemoryRateLimitStore struct {
+		mu      sync.Mutex
+		buckets map[rateLimitKey]*rateLimitState
+		swept   time.Time
+		now     func() time.Time
+	}
+
+	// rateLimitKey separates the states of the limiters sharing a store
+	rateLimitKey struct {
+		key   string
+		limit RateLimit
+	}
+
+	rateLimitState struct {
+		// The state of token bucket
+		tokens float64
+		last   time.Time
+
+		// The state of sliding window
+		start    time.Time
+		previous int
+		current  int
+
+		expires time.Time
+	}
+)
+
+// NewRateLimiter returns a rate limiter keyed by client IP, the states are
+// stored in memory.
+//
+//	limiter := fn.NewRateLimiter(fn.RateLimit{Limit: 100, Window: time.Minute}).
+//		Key(fn.KeyByHeader("X-API-Key"))
+//	group := fn.NewGroup().Plugin(limiter.Plugin)
+func NewRateLimiter(limit RateLimit) *RateLimiter {
+	if limit.Limit <= 0 || limit.Window <= 0 {
+		panic("rate limit should have a positive limit and window")
+	}
+	return &RateLimiter{
+		limit: limit,
+		key:   KeyByIP(false),
+		store: NewMemoryRateLimitStore(),
+	}
+}
+
+// Key sets how the requests are keyed
+func (l *RateLimiter) Key(key RateLimitKeyFunc) *RateLimiter {
+	l.key = key
+	return l
+}
+
+// Store sets where the states are stored
+func (l *RateLimiter) Store(store RateLimitStore) *RateLimiter {
+	l.store = store
+	return l
+}
+
+// Plugin is the PluginFunc limiting the requests, the RateLimit-Limit,
+// RateLimit-Remaining and RateLimit-Reset headers are set on the responses,
+// and the requests exceeding the limit are rejected by ErrRateLimited with
+// the Retry-After header.
+func (l *RateLimiter) Plugin(ctx context.Context, r *http.Request) (context.Context, error) {
+	key := l.key(ctx, r)
+	if key == "" {
+		return ctx, nil
+	}
+	result, err := l.store.Take(ctx, key, l.limit)
+	if err != nil {
+		if _, ok := UnwrapErrorStatusCode(err); ok {
+			return ctx, err
+		}
+		return ctx, ErrorWithStatusCode(err, http.StatusInternalServerError)
+	}
+
+	header := ResponseHeader(ctx)
+	header.Set("RateLimit-Limit", strconv.Itoa(l.limit.Limit))
+	header.Set("RateLimit-Remaining", strconv.Itoa(result.Remaining))
+	header.Set("RateLimit-Reset", strconv.FormatInt(ceilSeconds(result.Reset), 10))
+	if !result.Allowed {
+		retryAfter := ceilSeconds(result.RetryAfter)
+		if retryAfter < 1 {
+			retryAfter = 1
+		}
+		header.Set("Retry-After", strconv.FormatInt(retryAfter, 10))
+		return ctx, ErrorWithStatusCode(ErrRateLimited, http.StatusTooManyRequests)
+	}
+	return ctx, nil
+}
+
+// KeyByIP keys the requests by client IP, the X-Forwarded-For (or
+// X-Real-IP) header is used if the proxy is trusted
+func KeyByIP(trustProxy bool) RateLimitKeyFunc {
+	return func(ctx context.Context, r *http.Request) string {
+		return "ip:" + clientIP(r, trustProxy)
+	}
+}
+
+// KeyByHeader keys the requests by the value of header, e.g. the API key,
+// the requests without the header are not limited
+func KeyByHeader(name string) RateLimitKeyFunc {
+	return func(ctx context.Context, r *http.Request) string {
+		if v := r.Header.Get(name); v != "" {
+			return "header:" + name + ":" + v
+		}
+		return ""
+	}
+}
+
+// KeyByPrincipal keys the requests by the authenticated principal (see
+// WithPrincipal), the anonymous requests are not limited. The plugin
+// should run after the authentication plugin.
+func KeyByPrincipal() RateLimitKeyFunc {
+	return func(ctx context.Context, r *http.Request) string {
+		if principal, ok := PrincipalFromContext(ctx); ok {
+			return "principal:" + principal
+		}
+		return ""
+	}
+}
+
+// NewMemoryRateLimitStore returns an empty in-memory store
+func NewMemoryRateLimitStore() *MemoryRateLimitStore {
+	return &MemoryRateLimitStore{
+		buckets: map[rateLimitKey]*rateLimitState{},
+		now:     time.Now,
+	}
+}
+
+// Take implements the RateLimitStore interface
+func (s *MemoryRateLimitStore) Take(ctx context.Context, key string, limit RateLimit) (RateLimitResult, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := s.now()
+	if now.Sub(s.swept) >= memoryStoreSweepInterval {
+		for k, state := range s.buckets {
+			if now.After(state.expires) {
+				delete(s.buckets, k)
+			}
+		}
+		s.swept = now
+	}
+
+	k := rateLimitKey{key: key, limit: limit}
+	state, ok := s.buckets[k]
+	if !ok {
+		state = &rateLimitState{tokens: float64(limit.Limit), last: now, start: now.Truncate(limit.Window)}
+		s.buckets[k] = state
+	}
+
+	var result RateLimitResult
+	switch limit.Algorithm {
+	case SlidingWindow:
+		result = state.slidingWindow(now, limit)
+	default:
+		result = state.tokenBucket(now, limit)
+	}
+	state.expires = now.Add(2 * limit.Window)
+	return result, nil
+}
+
+func (state *rateLimitState) tokenBucket(now time.Time, limit RateLimit) RateLimitResult {
+	// Tokens refilled per second
+	rate := float64(limit.Limit) / limit.Window.Seconds()
+	if elapsed := now.Sub(state.last).Seconds(); elapsed > 0 {
+		state.tokens = math.Min(float64(limit.Limit), state.tokens+elapsed*rate)
+		state.last = now
+	}
+
+	result := RateLimitResult{}
+	if state.tokens >= 1 {
+		state.tokens--
+		result.Allowed = true
+	} else {
+		result.RetryAfter = secondsToDuration((1 - state.tokens) / rate)
+	}
+	result.Remaining = int(state.tokens)
+	result.Reset = secondsToDuration((float64(limit.Limit) - state.tokens) / rate)
+	return result
+}
+
+func (state *rateLimitState) slidingWindow(now time.Time, limit RateLimit) RateLimitResult {
+	start := now.Truncate(limit.Window)
+	switch {
+	case start.Sub(state.start) >= 2*limit.Window:
+		state.previous, state.current = 0, 0
+	case start.After(state.start):
+		state.previous, state.current = state.current, 0
+	}
+	state.start = start
+
+	// The fraction of current window elapsed
+	elapsed := float64(now.Sub(start)) / float64(limit.Window)
+	estimate := float64(state.previous)*(1-elapsed) + float64(state.current)
+
+	result := RateLimitResult{}
+	if estimate+1 <= float64(limit.Limit) {
+		state.current++
+		estimate++
+		result.Allowed = true
+	} else {
+		result.RetryAfter = state.retryAfter(elapsed, limit)
+	}
+	result.Remaining = int(math.Max(0, float64(limit.Limit)-math.Ceil(estimate)))
+	// The quota is fully restored when the count of current window expires
+	result.Reset = start.Add(2 * limit.Window).Sub(now)
+	if state.current == 0 {
+		result.Reset = start.Add(limit.Window).Sub(now)
+	}
+	return result
+}
+
+// retryAfter estimates the duration until a request is allowed
+func (state *rateLimitState) retryAfter(elapsed float64, limit RateLimit) time.Duration {
+	window := limit.Window.Seconds()
+	available := float64(limit.Limit - 1)
+	if float64(state.current) <= available && state.previous > 0 {
+		// Allowed in the current window once the weight of the previous
+		// window decreases enough
+		need := 1 - (available-float64(state.current))/float64(state.previous)
+		return secondsToDuration((need - elapsed) * window)
+	}
+	// Allowed in the next window, whose previous window is the current one
+	wait := (1 - elapsed) * window
+	if state.current > 0 {
+		if need := 1 - available/float64(state.current); need > 0 {
+			wait += need * window
+		}
+	}
+	return secondsToDuration(wait)
+}
+
+func secondsToDuration(s float64) time.Duration {
+	if s < 0 {
+		return 0
+	}
+	return time.Duration(s * float64(time.Second))
+}
+
+func ceilSeconds(d time.Duration) int64 {
+	return int64(math.Ceil(d.Seconds()))
+}
@@ -0,0 +1,164 @@
+// Copyright 2026 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package fn
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestRateLimiter(t *testing.T) {
+	SetErrorEncoder(func(ctx context.Context, err error) interface{} {
+		return err.Error()
+	})
+
+	now := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+	store := NewMemoryRateLimitStore()
+	store.now = func() time.Time { return now }
+
+	limiter := NewRateLimiter(RateLimit{Limit: 2, Window: 10 * time.Second}).Store(store)
+	handler := NewGroup().Plugin(limiter.Plugin).Wrap(func() (*testResponse, error) {
+		return &testResponse{Message: "ok"}, nil
+	})
+
+	serve := func(remoteAddr string) *httptest.ResponseRecorder {
+		request := httptest.NewRequest(http.MethodGet, "/", nil)
+		request.RemoteAddr = remoteAddr
+		recorder := httptest.NewRecorder()
+		handler.ServeHTTP(recorder, request)
+		return recorder
+	}
+
+	recorder := serve("10.0.0.1:1000")
+	require.Equal(t, http.StatusOK, recorder.Code)
+	require.Equal(t, "2", recorder.Header().Get("RateLimit-Limit"))
+	require.Equal(t, "1", recorder.Header().Get("RateLimit-Remaining"))
+	require.Equal(t, "5", recorder.Header().Get("RateLimit-Reset"))
+
+	require.Equal(t, http.StatusOK, serve("10.0.0.1:1001").Code)
+	recorder = serve("10.0.0.1:1002")
+	require.Equal(t, http.StatusTooManyRequests, recorder.Code)
+	require.Equal(t, `"rate limit exceeded"`+"\n", recorder.Body.String())
+	require.Equal(t, "0", recorder.Header().Get("RateLimit-Remaining"))
+	require.Equal(t, "5", recorder.Header().Get("Retry-After"))
+
+	// Keyed by client IP
+	require.Equal(t, http.StatusOK, serve("10.0.0.2:1000").Code)
+
+	// Refilled at the rate of 1 token per 5 seconds
+	now = now.Add(5 * time.Second)
+	require.Equal(t, http.StatusOK, serve("10.0.0.1:1003").Code)
+	require.Equal(t, http.StatusTooManyRequests, serve("10.0.0.1:1004").Code)
+
+	// The idle states are evicted
+	now = now.Add(time.Hour)
+	require.Equal(t, http.StatusOK, serve("10.0.0.1:1005").Code)
+	require.Len(t, store.buckets, 1)
+
+	// The limiters sharing the store don't share the states
+	strict := NewRateLimiter(RateLimit{Algorithm: SlidingWindow, Limit: 1, Window: 10 * time.Second}).Store(store)
+	handler = NewGroup().Plugin(strict.Plugin).Wrap(func() (*testResponse, error) {
+		return &testResponse{Message: "ok"}, nil
+	})
+	recorder = serve("10.0.0.1:1006")
+	require.Equal(t, http.StatusOK, recorder.Code)
+	require.Equal(t, "0", recorder.Header().Get("RateLimit-Remaining"))
+	require.Equal(t, http.StatusTooManyRequests, serve("10.0.0.1:1007").Code)
+	require.Len(t, store.buckets, 2)
+}
+
+func TestRateLimiterKeys(t *testing.T) {
+	limiter := NewRateLimiter(RateLimit{Limit: 1, Window: time.Minute})
+
+	authenticate := func(ctx context.Context, r *http.Request) (context.Context, error) {
+		if user := r.Header.Get("X-User"); user != "" {
+			return WithPrincipal(ctx, user), nil
+		}
+		return ctx, nil
+	}
+	handler := Wrap(func() error { return nil }).Plugin(authenticate, limiter.Key(KeyByPrincipal()).Plugin)
+
+	serve := func(user string) int {
+		request := httptest.NewRequest(http.MethodGet, "/", nil)
+		request.Header.Set("X-User", user)
+		recorder := httptest.NewRecorder()
+		handler.ServeHTTP(recorder, request)
+		return recorder.Code
+	}
+	require.Equal(t, http.StatusNoContent, serve("alice"))
+	require.Equal(t, http.StatusTooManyRequests, serve("alice"))
+	require.Equal(t, http.StatusNoContent, serve("bob"))
+	// The anonymous requests are not limited
+	require.Equal(t, http.StatusNoContent, serve(""))
+	require.Equal(t, http.StatusNoContent, serve(""))
+
+	request := httptest.NewRequest(http.MethodGet, "/", nil)
+	request.RemoteAddr = "10.0.0.1:1000"
+	request.Header.Set("X-Forwarded-For", "192.168.0.1, 10.0.0.1")
+	request.Header.Set("X-API-Key", "secret")
+	ctx := context.Background()
+	require.Equal(t, "ip:10.0.0.1", KeyByIP(false)(ctx, request))
+	require.Equal(t, "ip:192.168.0.1", KeyByIP(true)(ctx, request))
+	require.Equal(t, "header:X-API-Key:secret", KeyByHeader("X-API-Key")(ctx, request))
+	require.Equal(t, "", KeyByHeader("X-Other")(ctx, request))
+}
+
+func TestSlidingWindow(t *testing.T) {
+	start := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+	now := start
+	store := NewMemoryRateLimitStore()
+	store.now = func() time.Time { return now }
+	limit := RateLimit{Algorithm: SlidingWindow, Limit: 4, Window: 10 * time.Second}
+
+	take := func() RateLimitResult {
+		result, err := store.Take(context.Background(), "key", limit)
+		require.NoError(t, err)
+		return result
+	}
+
+	for i := 3; i >= 0; i-- {
+		result := take()
+		require.True(t, result.Allowed)
+		require.Equal(t, i, result.Remaining)
+	}
+	result := take()
+	require.False(t, result.Allowed)
+	require.Equal(t, 12500*time.Millisecond, result.RetryAfter)
+
+	// 4 requests in the previous window weighted by 75%
+	now = start.Add(12500 * time.Millisecond)
+	result = take()
+	require.True(t, result.Allowed)
+	require.Equal(t, 0, result.Remaining)
+	result = take()
+	require.False(t, result.Allowed)
+	// Allowed once the weight of the previous window decreases to 50%
+	require.Equal(t, 2500*time.Millisecond, result.RetryAfter)
+	require.Equal(t, 17500*time.Millisecond, result.Reset)
+
+	now = start.Add(15 * time.Second)
+	require.True(t, take().Allowed)
+
+	// Reset after 2 windows
+	now = start.Add(time.Minute)
+	result = take()
+	require.True(t, result.Allowed)
+	require.Equal(t, 3, result.Remaining)
+}
@@ -0,0 +1,127 @@
+// Copyright 2026 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package fn
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"net/http"
+)
+
+// DefaultRequestIDHeader is the header carrying the request id
+const DefaultRequestIDHeader = "X-Request-ID"
+
+const maxRequestIDLength = 128
+
+type (
+	// RequestIDOptions controls how the request id is read, generated and
+	// echoed
+	RequestIDOptions struct {
+		// Header is the request and response header carrying the request
+		// id, DefaultRequestIDHeader if empty
+		Header string
+		// Validate reports whether the incoming request id is acceptable,
+		// the invalid ones are replaced by generated ones. The default one
+		// accepts up to 128 letters, digits and -_.:+/=@
+		Validate func(id string) bool
+		// Generate returns a new request id, 32 random hex digits by default
+		Generate func() string
+	}
+
+	requestIDKey struct{}
+)
+
+// SetRequestID enables assigning a request id to every request served by the
+// wrapped handlers, nil disables it. The incoming request id is used if it
+// is valid, otherwise a new one is generated. The request id is echoed as a
+// response header, and available in the context of plugins, the wrapped
+// function and the ErrorEncoder (see RequestIDFromContext).
+//
+//	fn.SetRequestID(&fn.RequestIDOptions{})
+//	fn.SetErrorEncoder(func(ctx context.Context, err error) interface{} {
+//		return &Error{Message: err.Error(), RequestID: fn.RequestIDFromContext(ctx)}
+//	})
+func SetRequestID(opts *RequestIDOptions) {
+	if opts == nil {
+		observers[requestIDSlot] = nil
+		return
+	}
+	o := *opts
+	if o.Header == "" {
+		o.Header = DefaultRequestIDHeader
+	}
+	if o.Validate == nil {
+		o.Validate = validRequestID
+	}
+	if o.Generate == nil {
+		o.Generate = newRequestID
+	}
+	observers[requestIDSlot] = &o
+}
+
+// RequestIDFromContext returns the request id, or empty string if it is not
+// assigned
+func RequestIDFromContext(ctx context.Context) string {
+	id, _ := ctx.Value(requestIDKey{}).(string)
+	return id
+}
+
+func (o *RequestIDOptions) observe(ctx context.Context, x *exchange) (context.Context, probe) {
+	return o.assign(ctx, x.writer, x.request), nil
+}
+
+// assign reads or generates the request id, echoes it and stores it in the
+// context
+func (o *RequestIDOptions) assign(ctx context.Context, w http.ResponseWriter, r *http.Request) context.Context {
+	id := r.Header.Get(o.Header)
+	if id == "" || !o.Validate(id) {
+		id = o.Generate()
+	}
+	w.Header().Set(o.Header, id)
+	return context.WithValue(ctx, requestIDKey{}, id)
+}
+
+// withRequestID assigns the request id for the handlers serving requests
+// without the wrapper, e.g. JSON-RPC and batch
+func withRequestID(w http.ResponseWriter, r *http.Request) *http.Request {
+	o, ok := observers[requestIDSlot].(*RequestIDOptions)
+	if !ok {
+		return r
+	}
+	return r.WithContext(o.assign(r.Context(), w, r))
+}
+
+func validRequestID(id string) bool {
+	if len(id) > maxRequestIDLength {
+		return false
+	}
+	for i := 0; i < len(id); i++ {
+		c := id[i]
+		switch {
+		case c >= 'a' && c <= 'z', c >= 'A' && c <= 'Z', c >= '0' && c <= '9':
+		case c == '-', c == '_', c == '.', c == ':', c == '+', c == '/', c == '=', c == '@':
+		default:
+			return false
+		}
+	}
+	return true
+}
+
+func newRequestID() string {
+	var id [16]byte
+	_, _ = rand.Read(id[:])
+	return hex.EncodeToString(id[:])
+}
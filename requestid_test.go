@@ -0,0 +1,112 @@
+// Copyright 2026 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package fn
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestRequestID(t *testing.T) {
+	SetErrorEncoder(func(ctx context.Context, err error) interface{} {
+		return map[string]string{"message": err.Error(), "request_id": RequestIDFromContext(ctx)}
+	})
+	SetResponseEncoder(func(ctx context.Context, payload interface{}) interface{} {
+		return payload
+	})
+	defer SetErrorEncoder(func(ctx context.Context, err error) interface{} {
+		return err.Error()
+	})
+
+	var seen string
+	handler := Wrap(func(ctx context.Context) (*testResponse, error) {
+		seen = RequestIDFromContext(ctx)
+		return nil, ErrorWithStatusCode(errors.New("conflict"), http.StatusConflict)
+	})
+
+	serve := func(header, id string) (*httptest.ResponseRecorder, map[string]string) {
+		request := httptest.NewRequest(http.MethodGet, "/", nil)
+		if id != "" {
+			request.Header.Set(header, id)
+		}
+		recorder := httptest.NewRecorder()
+		handler.ServeHTTP(recorder, request)
+		body := map[string]string{}
+		require.NoError(t, json.Unmarshal(recorder.Body.Bytes(), &body))
+		return recorder, body
+	}
+
+	// Disabled by default
+	recorder, body := serve(DefaultRequestIDHeader, "abc")
+	require.Empty(t, recorder.Header().Get(DefaultRequestIDHeader))
+	require.Empty(t, body["request_id"])
+	require.Empty(t, seen)
+
+	SetRequestID(&RequestIDOptions{})
+	defer SetRequestID(nil)
+
+	recorder, body = serve(DefaultRequestIDHeader, "abc-123")
+	require.Equal(t, "abc-123", recorder.Header().Get(DefaultRequestIDHeader))
+	require.Equal(t, "abc-123", body["request_id"])
+	require.Equal(t, "abc-123", seen)
+
+	for _, invalid := range []string{"", "has space", "new\nline", strings.Repeat("a", 129)} {
+		recorder, body = serve(DefaultRequestIDHeader, invalid)
+		id := recorder.Header().Get(DefaultRequestIDHeader)
+		require.Len(t, id, 32)
+		require.NotEqual(t, invalid, id)
+		require.Equal(t, id, body["request_id"])
+		require.Equal(t, id, seen)
+	}
+
+	n := 0
+	SetRequestID(&RequestIDOptions{
+		Header:   "X-Correlation-ID",
+		Validate: func(id string) bool { return strings.HasPrefix(id, "corr-") },
+		Generate: func() string { n++; return "generated" },
+	})
+	recorder, _ = serve("X-Correlation-ID", "corr-1")
+	require.Equal(t, "corr-1", recorder.Header().Get("X-Correlation-ID"))
+	require.Empty(t, recorder.Header().Get(DefaultRequestIDHeader))
+	recorder, body = serve("X-Correlation-ID", "other")
+	require.Equal(t, "generated", recorder.Header().Get("X-Correlation-ID"))
+	require.Equal(t, "generated", body["request_id"])
+	require.Equal(t, 1, n)
+}
+
+func TestRequestIDJSONRPC(t *testing.T) {
+	SetRequestID(&RequestIDOptions{})
+	defer SetRequestID(nil)
+
+	var seen string
+	rpc := NewJSONRPC().Register("ping", func(ctx context.Context) (string, error) {
+		seen = RequestIDFromContext(ctx)
+		return "pong", nil
+	})
+
+	request := httptest.NewRequest(http.MethodPost, "/rpc", strings.NewReader(`{"jsonrpc":"2.0","method":"ping","id":1}`))
+	request.Header.Set(DefaultRequestIDHeader, "rpc-1")
+	recorder := httptest.NewRecorder()
+	rpc.ServeHTTP(recorder, request)
+	require.Equal(t, "rpc-1", recorder.Header().Get(DefaultRequestIDHeader))
+	require.Equal(t, "rpc-1", seen)
+}
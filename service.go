@@ -0,0 +1,209 @@
+// Copyright 2026 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package fn
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"reflect"
+	"strings"
+	"unicode"
+)
+
+type (
+	// ServiceOptions controls where and how the methods of a service are
+	// mounted by RegisterService
+	ServiceOptions struct {
+		// Mux is where the endpoints are mounted, http.DefaultServeMux if nil
+		Mux *http.ServeMux
+		// Prefix is the path prefix of the endpoints, "/" followed by the
+		// name of service type if empty
+		Prefix string
+		// Naming converts the method name to the last segment of the path,
+		// the method name is used as is if nil, e.g. KebabCase
+		Naming func(method string) string
+	}
+
+	// MethodOverride customizes how a method is registered
+	MethodOverride struct {
+		// Pattern replaces the pattern built from the naming convention
+		Pattern string
+		// Skip leaves the method unregistered
+		Skip bool
+		// Plugins are appended to the plugins of the group
+		Plugins []PluginFunc
+		// Requirements are appended to the requirements of the group
+		Requirements []string
+	}
+
+	// ServiceOverrider can be implemented by a service to customize the
+	// registration of its methods
+	ServiceOverrider interface {
+		OverrideMethod(method string) MethodOverride
+	}
+
+	// ServiceRoute represents an endpoint mounted by RegisterService
+	ServiceRoute struct {
+		Method       string
+		Pattern      string
+		Requirements []string
+	}
+
+	// SkippedMethod represents an exported method which is not mounted
+	SkippedMethod struct {
+		Method string
+		Reason string
+	}
+
+	// ServiceReport reports the result of RegisterService
+	ServiceReport struct {
+		Routes  []ServiceRoute
+		Skipped []SkippedMethod
+	}
+)
+
+var overrideMethodName = reflect.TypeOf((*ServiceOverrider)(nil)).Elem().Method(0).Name
+
+// RegisterService mounts every exported method of svc of the shape below as an
+// endpoint (like net/rpc does), the request parameter is optional. The other
+// methods are skipped even if they are acceptable by Wrap, and the methods
+// are wrapped by the group so that they share the group plugins and options.
+//
+//	func (s *UserService) Get(ctx context.Context, req *GetRequest) (*User, error)
+//
+//	// Mounted at /UserService/Get
+//	report, err := fn.RegisterService(group, &UserService{}, nil)
+func RegisterService(g *Group, svc interface{}, opts *ServiceOptions) (*ServiceReport, error) {
+	if svc == nil {
+		return nil, errors.New("fn: nil service")
+	}
+	if g == nil {
+		g = NewGroup()
+	}
+	if opts == nil {
+		opts = &ServiceOptions{}
+	}
+
+	mux := opts.Mux
+	if mux == nil {
+		mux = http.DefaultServeMux
+	}
+
+	typ := reflect.TypeOf(svc)
+	prefix := opts.Prefix
+	if prefix == "" {
+		prefix = "/" + indirectType(typ).Name()
+	}
+	prefix = strings.TrimSuffix(prefix, "/")
+
+	overrider, _ := svc.(ServiceOverrider)
+	value := reflect.ValueOf(svc)
+	report := &ServiceReport{}
+	for i := 0; i < typ.NumMethod(); i++ {
+		method := typ.Method(i)
+		if method.PkgPath != "" || (overrider != nil && method.Name == overrideMethodName) {
+			continue
+		}
+
+		var override MethodOverride
+		if overrider != nil {
+			override = overrider.OverrideMethod(method.Name)
+		}
+		if override.Skip {
+			report.Skipped = append(report.Skipped, SkippedMethod{Method: method.Name, Reason: "skipped by override"})
+			continue
+		}
+
+		if reason := checkServiceMethod(method.Type); reason != "" {
+			report.Skipped = append(report.Skipped, SkippedMethod{Method: method.Name, Reason: reason})
+			continue
+		}
+		handler, reason := wrapMethod(g, value.Method(i))
+		if handler == nil {
+			report.Skipped = append(report.Skipped, SkippedMethod{Method: method.Name, Reason: reason})
+			continue
+		}
+		handler.Plugin(override.Plugins...).Require(override.Requirements...)
+
+		pattern := override.Pattern
+		if pattern == "" {
+			name := method.Name
+			if opts.Naming != nil {
+				name = opts.Naming(name)
+			}
+			pattern = prefix + "/" + name
+		}
+		mux.Handle(pattern, handler.Name(pattern))
+		report.Routes = append(report.Routes, ServiceRoute{
+			Method:       method.Name,
+			Pattern:      pattern,
+			Requirements: handler.Requirements(),
+		})
+	}
+
+	if len(report.Routes) == 0 {
+		return report, fmt.Errorf("fn: type %s has no exported methods of suitable type", typ)
+	}
+	return report, nil
+}
+
+// checkServiceMethod returns the reason if the method is not of the shape
+// func(ctx context.Context[, req Request]) (Response, error), so that the
+// other methods acceptable by Wrap (e.g. Close() error) are not exposed
+func checkServiceMethod(t reflect.Type) string {
+	// The receiver is the first parameter of method type
+	switch {
+	case t.NumIn() < 2 || t.In(1) != contextType:
+		return "unsupported function type, the first parameter should be context.Context"
+	case t.NumIn() > 3:
+		return "unsupported function type, at most one request parameter is accepted"
+	case t.NumIn() == 3 && !consumesBody(t.In(2)):
+		return "unsupported function type, the request should be decoded from the body"
+	case t.NumOut() != 2 || t.Out(1) != errorType:
+		return "unsupported function type, the return values should be response data & error"
+	}
+	return ""
+}
+
+// wrapMethod wraps the method by the group, the reason is returned instead
+// if the signature is unacceptable
+func wrapMethod(g *Group, method reflect.Value) (handler *fn, reason string) {
+	defer func() {
+		if r := recover(); r != nil {
+			handler, reason = nil, fmt.Sprint(r)
+		}
+	}()
+	return g.Wrap(method.Interface()), ""
+}
+
+// KebabCase converts the method name to kebab case, e.g. GetUserByID is
+// converted to get-user-by-id
+func KebabCase(name string) string {
+	runes := []rune(name)
+	b := strings.Builder{}
+	for i, r := range runes {
+		if unicode.IsUpper(r) {
+			// Start a new word at a lower to upper boundary, or at the last
+			// upper case letter of an acronym followed by a lower case one
+			if i > 0 && (!unicode.IsUpper(runes[i-1]) || (i+1 < len(runes) && unicode.IsLower(runes[i+1]))) {
+				b.WriteByte('-')
+			}
+			r = unicode.ToLower(r)
+		}
+		b.WriteRune(r)
+	}
+	return b.String()
+}
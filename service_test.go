@@ -0,0 +1,109 @@
+// Copyright 2026 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package fn
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+type testService struct {
+	prefix string
+}
+
+func (s *testService) Echo(ctx context.Context, req *testRequest) (*testResponse, error) {
+	return &testResponse{Code: req.Bar, Message: s.prefix + req.Foo}, nil
+}
+
+func (s *testService) GetUserByID(ctx context.Context) (*testResponse, error) {
+	return &testResponse{Message: s.prefix + ctx.Value("key").(string)}, nil
+}
+
+func (s *testService) Internal() error { return nil }
+
+func (s *testService) Close() error { return nil }
+
+func (s *testService) Notify(ctx context.Context, req *testRequest) error { return nil }
+
+func (s *testService) Raw(ctx context.Context, r *http.Request) (*testResponse, error) {
+	return nil, nil
+}
+
+func (s *testService) String() string { return "testService" }
+
+func (s *testService) OverrideMethod(method string) MethodOverride {
+	switch method {
+	case "GetUserByID":
+		return MethodOverride{
+			Pattern: "/users/get",
+			Plugins: []PluginFunc{func(ctx context.Context, r *http.Request) (context.Context, error) {
+				return context.WithValue(ctx, "key", "value"), nil
+			}},
+		}
+	case "Internal":
+		return MethodOverride{Skip: true}
+	}
+	return MethodOverride{}
+}
+
+func TestRegisterService(t *testing.T) {
+	mux := http.NewServeMux()
+	report, err := RegisterService(NewGroup(), &testService{prefix: "svc:"}, &ServiceOptions{Mux: mux, Naming: KebabCase})
+	require.NoError(t, err)
+	require.Equal(t, []ServiceRoute{
+		{Method: "Echo", Pattern: "/testService/echo"},
+		{Method: "GetUserByID", Pattern: "/users/get"},
+	}, report.Routes)
+	require.Equal(t, []SkippedMethod{
+		{Method: "Close", Reason: "unsupported function type, the first parameter should be context.Context"},
+		{Method: "Internal", Reason: "skipped by override"},
+		{Method: "Notify", Reason: "unsupported function type, the return values should be response data & error"},
+		{Method: "Raw", Reason: "unsupported function type, the request should be decoded from the body"},
+		{Method: "String", Reason: "unsupported function type, the first parameter should be context.Context"},
+	}, report.Skipped)
+
+	recorder := httptest.NewRecorder()
+	mux.ServeHTTP(recorder, httptest.NewRequest(http.MethodPost, "/testService/close", nil))
+	require.Equal(t, http.StatusNotFound, recorder.Code)
+
+	recorder = httptest.NewRecorder()
+	mux.ServeHTTP(recorder, httptest.NewRequest(http.MethodPost, "/testService/echo", strings.NewReader(`{"foo":"hello","bar":1}`)))
+	require.Equal(t, http.StatusOK, recorder.Code)
+	require.JSONEq(t, `{"code":1,"message":"svc:hello"}`, recorder.Body.String())
+
+	recorder = httptest.NewRecorder()
+	mux.ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, "/users/get", nil))
+	require.Equal(t, http.StatusOK, recorder.Code)
+	require.JSONEq(t, `{"code":0,"message":"svc:value"}`, recorder.Body.String())
+
+	_, err = RegisterService(nil, struct{}{}, &ServiceOptions{Mux: http.NewServeMux()})
+	require.Error(t, err)
+}
+
+func TestKebabCase(t *testing.T) {
+	for name, expect := range map[string]string{
+		"Echo":        "echo",
+		"GetUserByID": "get-user-by-id",
+		"HTTPServer":  "http-server",
+		"ListV2":      "list-v2",
+	} {
+		require.Equal(t, expect, KebabCase(name))
+	}
+}
@@ -0,0 +1,59 @@
+// Copyright 2026 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package fn
+
+import (
+	"net/http"
+	"reflect"
+)
+
+// binder is implemented by the pointers to the parameter types which declare
+// the source they are bound from, e.g. *Query[T] and *Body[T]. A function can
+// accept any number of them, but only one parameter can consume the body.
+type binder interface {
+	bindRequest(r *http.Request, opts DecodeOption) error
+	fromBody() bool
+}
+
+var binderType = reflect.TypeOf((*binder)(nil)).Elem()
+
+func isBinder(t reflect.Type) bool {
+	return t.Kind() != reflect.Ptr && reflect.PtrTo(t).Implements(binderType)
+}
+
+// checkBinder panics if the binder reading the fields of request (e.g.
+// Query[T]) doesn't bind a struct, which can never be bound
+func checkBinder(t reflect.Type) {
+	if reflect.New(t).Interface().(binder).fromBody() {
+		return
+	}
+	if f, ok := t.FieldByName("Value"); ok && indirectType(f.Type).Kind() != reflect.Struct {
+		panic("unsupported binding type(" + t.String() + "), a struct is required")
+	}
+}
+
+// consumesBody reports whether the parameter of type t is read from the body
+func consumesBody(t reflect.Type) bool {
+	if isBinder(t) {
+		return reflect.New(t).Interface().(binder).fromBody()
+	}
+	return t != contextType && !isBuiltinType(t) && !isContextValueType(t) && !isFormBinding(t)
+}
+
+func headerSource(header http.Header) valueSource {
+	return func(key string) []string {
+		return header[http.CanonicalHeaderKey(key)]
+	}
+}
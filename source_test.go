@@ -0,0 +1,83 @@
+//go:build go1.22
+// +build go1.22
+
+// Copyright 2026 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package fn
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+type testTenant struct {
+	ID    string `header:"X-Tenant-ID"`
+	Trace string `header:"X-Trace" default:"none"`
+}
+
+type testItemPath struct {
+	Shop string `path:"shop"`
+	ID   int64  `path:"id"`
+}
+
+func TestExplicitSources(t *testing.T) {
+	handler := Wrap(func(ctx context.Context, path Path[testItemPath], query Query[testPaging], tenant Headers[*testTenant], body Body[[]testRequest]) (*testResponse, error) {
+		var names []string
+		for _, item := range body.Value {
+			names = append(names, item.Foo)
+		}
+		return &testResponse{
+			Code:    int(path.Value.ID)*100 + query.Value.Page*10 + query.Value.Size,
+			Message: strings.Join([]string{path.Value.Shop, tenant.Value.ID, tenant.Value.Trace, strings.Join(names, ",")}, "|"),
+		}, nil
+	})
+
+	// The path values are set by http.ServeMux with pattern "POST /shops/{shop}/items/{id}"
+	recorder := httptest.NewRecorder()
+	request := httptest.NewRequest(http.MethodPost, "/shops/acme/items/7?page=2&size=5", strings.NewReader(`[{"foo":"a"},{"foo":"b"}]`))
+	request.SetPathValue("shop", "acme")
+	request.SetPathValue("id", "7")
+	request.Header.Set("X-Tenant-ID", "t1")
+	handler.ServeHTTP(recorder, request)
+	require.Equal(t, http.StatusOK, recorder.Code)
+	require.JSONEq(t, `{"code":725,"message":"acme|t1|none|a,b"}`, recorder.Body.String())
+
+	recorder = httptest.NewRecorder()
+	request = httptest.NewRequest(http.MethodPost, "/shops/acme/items/x", strings.NewReader(`[]`))
+	request.SetPathValue("id", "x")
+	handler.ServeHTTP(recorder, request)
+	require.Equal(t, http.StatusBadRequest, recorder.Code)
+}
+
+func TestExplicitSourcesSignature(t *testing.T) {
+	Wrap(func(Query[testPaging]) error { return nil })
+	Wrap(func(Body[testRequest]) error { return nil })
+	Wrap(func(Query[testPaging], *testRequest) error { return nil })
+	Wrap(func(Query[testPaging], Headers[testTenant], Body[testRequest], http.Header) error { return nil })
+
+	require.Panics(t, func() { Wrap(func(Body[testRequest], *testRequest) error { return nil }) })
+	require.Panics(t, func() { Wrap(func(Body[testRequest], Body[testPaging]) error { return nil }) })
+
+	// The bindings of request fields require structs
+	require.Panics(t, func() { Wrap(func(Query[string]) error { return nil }) })
+	require.Panics(t, func() { Wrap(func(Headers[[]string]) error { return nil }) })
+	require.Panics(t, func() { Wrap(func(Path[int]) error { return nil }) })
+	require.NotPanics(t, func() { Wrap(func(Query[*testPaging], Body[[]int]) error { return nil }) })
+}
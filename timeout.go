@@ -0,0 +1,169 @@
+// Copyright 2026 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package fn
+
+import (
+	"bytes"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+)
+
+var (
+	timeout          time.Duration
+	timeoutHeader    string
+	maxClientTimeout time.Duration
+)
+
+// timeoutWriter buffers the response of a handler running with a timeout,
+// the response is dropped if the timeout response has been sent
+type timeoutWriter struct {
+	w http.ResponseWriter
+
+	mu          sync.Mutex
+	header      http.Header
+	status      int
+	buf         bytes.Buffer
+	timedOut    bool
+	wroteHeader bool
+}
+
+// SetTimeout sets the default timeout of handlers, the context of request
+// is canceled when the timeout expires and 503 is responded through the
+// ErrorEncoder. Zero or negative means no timeout. The panics of handlers
+// after the timeout response are logged by the standard logger, instead of
+// being re-raised.
+func SetTimeout(d time.Duration) {
+	timeout = d
+}
+
+// SetClientTimeoutHeader honours the timeout supplied by clients in the
+// header, e.g. X-Request-Timeout: 500ms (a duration, or an integer in
+// milliseconds), which is capped by max and the timeout of handler. 504 is
+// responded if the timeout supplied by client expires. Empty header disables
+// it, zero or negative max means no cap except the timeout of handler.
+func SetClientTimeoutHeader(header string, max time.Duration) {
+	timeoutHeader = header
+	maxClientTimeout = max
+}
+
+// effectiveTimeout returns the timeout of the request and the status code
+// responded when it expires, zero means no timeout
+func effectiveTimeout(d time.Duration, r *http.Request) (time.Duration, int) {
+	if d == 0 {
+		d = timeout
+	}
+	if d < 0 {
+		d = 0
+	}
+
+	client := clientTimeout(r)
+	if client > 0 && maxClientTimeout > 0 && client > maxClientTimeout {
+		client = maxClientTimeout
+	}
+	if client > 0 && (d == 0 || client < d) {
+		return client, http.StatusGatewayTimeout
+	}
+	return d, http.StatusServiceUnavailable
+}
+
+// clientTimeout parses the timeout supplied by client, zero if absent or
+// invalid
+func clientTimeout(r *http.Request) time.Duration {
+	if timeoutHeader == "" {
+		return 0
+	}
+	v := r.Header.Get(timeoutHeader)
+	if v == "" {
+		return 0
+	}
+	if ms, err := strconv.ParseInt(v, 10, 64); err == nil {
+		if ms <= 0 || ms > int64(1<<63-1)/int64(time.Millisecond) {
+			return 0
+		}
+		return time.Duration(ms) * time.Millisecond
+	}
+	d, err := time.ParseDuration(v)
+	if err != nil || d < 0 {
+		return 0
+	}
+	return d
+}
+
+func newTimeoutWriter(w http.ResponseWriter) *timeoutWriter {
+	return &timeoutWriter{w: w, header: cloneHeader(w.Header())}
+}
+
+func (tw *timeoutWriter) Header() http.Header {
+	return tw.header
+}
+
+func (tw *timeoutWriter) WriteHeader(statusCode int) {
+	tw.mu.Lock()
+	defer tw.mu.Unlock()
+	if tw.timedOut || tw.wroteHeader {
+		return
+	}
+	tw.status = statusCode
+	tw.wroteHeader = true
+}
+
+func (tw *timeoutWriter) Write(p []byte) (int, error) {
+	tw.mu.Lock()
+	defer tw.mu.Unlock()
+	if tw.timedOut {
+		return 0, http.ErrHandlerTimeout
+	}
+	if !tw.wroteHeader {
+		tw.status = http.StatusOK
+		tw.wroteHeader = true
+	}
+	return tw.buf.Write(p)
+}
+
+// flush writes the buffered response
+func (tw *timeoutWriter) flush() {
+	tw.mu.Lock()
+	defer tw.mu.Unlock()
+	dst := tw.w.Header()
+	for key := range dst {
+		if _, ok := tw.header[key]; !ok {
+			delete(dst, key)
+		}
+	}
+	for key, values := range tw.header {
+		dst[key] = values
+	}
+	if tw.wroteHeader {
+		tw.w.WriteHeader(tw.status)
+	}
+	_, _ = tw.w.Write(tw.buf.Bytes())
+}
+
+// timeout drops the response written later
+func (tw *timeoutWriter) timeout() {
+	tw.mu.Lock()
+	tw.timedOut = true
+	tw.mu.Unlock()
+}
+
+func cloneHeader(h http.Header) http.Header {
+	c := make(http.Header, len(h))
+	for key, values := range h {
+		c[key] = append([]string(nil), values...)
+	}
+	return c
+}
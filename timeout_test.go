@@ -0,0 +1,194 @@
+// Copyright 2026 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package fn
+
+import (
+	"context"
+	"log"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestTimeout(t *testing.T) {
+	SetErrorEncoder(func(ctx context.Context, err error) interface{} {
+		return err.Error()
+	})
+	SetResponseEncoder(func(ctx context.Context, payload interface{}) interface{} {
+		return payload
+	})
+
+	var deadline bool
+	released := make(chan struct{})
+	group := NewGroup().Timeout(20 * time.Millisecond).Plugin(func(ctx context.Context, r *http.Request) (context.Context, error) {
+		_, deadline = ctx.Deadline()
+		return ctx, nil
+	})
+
+	// The handler ignoring the cancellation can't write after the timeout
+	// response is sent
+	slow := group.Wrap(func(ctx context.Context) (*testResponse, error) {
+		defer close(released)
+		time.Sleep(60 * time.Millisecond)
+		return &testResponse{Message: "late"}, nil
+	})
+	recorder := httptest.NewRecorder()
+	slow.ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, "/", nil))
+	require.True(t, deadline)
+	require.Equal(t, http.StatusServiceUnavailable, recorder.Code)
+	require.Equal(t, `"context deadline exceeded"`+"\n", recorder.Body.String())
+	<-released
+	require.Equal(t, `"context deadline exceeded"`+"\n", recorder.Body.String())
+
+	// The handler respecting the cancellation
+	waiting := group.Wrap(func(ctx context.Context) (*testResponse, error) {
+		<-ctx.Done()
+		return nil, ctx.Err()
+	})
+	recorder = httptest.NewRecorder()
+	waiting.ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, "/", nil))
+	require.Equal(t, http.StatusServiceUnavailable, recorder.Code)
+
+	// The response is sent as is if the handler finishes in time
+	fast := group.Wrap(func() (*testResponse, int, error) {
+		return &testResponse{Message: "fast"}, http.StatusCreated, nil
+	})
+	recorder = httptest.NewRecorder()
+	fast.ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, "/", nil))
+	require.Equal(t, http.StatusCreated, recorder.Code)
+	require.Equal(t, "application/json; charset=utf-8", recorder.Header().Get("Content-Type"))
+	require.JSONEq(t, `{"code":0,"message":"fast"}`, recorder.Body.String())
+
+	// The timeout of handler overrides the group one
+	deadline = false
+	unlimited := group.Wrap(func(ctx context.Context) (*testResponse, error) {
+		time.Sleep(30 * time.Millisecond)
+		return &testResponse{Message: "done"}, nil
+	}).Timeout(-1)
+	recorder = httptest.NewRecorder()
+	unlimited.ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, "/", nil))
+	require.False(t, deadline)
+	require.Equal(t, http.StatusOK, recorder.Code)
+
+	// The global timeout
+	SetTimeout(10 * time.Millisecond)
+	defer SetTimeout(0)
+	global := Wrap(func(ctx context.Context) error {
+		<-ctx.Done()
+		return ctx.Err()
+	})
+	recorder = httptest.NewRecorder()
+	global.ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, "/", nil))
+	require.Equal(t, http.StatusServiceUnavailable, recorder.Code)
+
+	// The panics are propagated
+	panicking := Wrap(func() error {
+		panic("boom")
+	})
+	require.PanicsWithValue(t, "boom", func() {
+		panicking.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/", nil))
+	})
+}
+
+type logWriter chan string
+
+func (w logWriter) Write(p []byte) (int, error) {
+	w <- string(p)
+	return len(p), nil
+}
+
+func TestTimeoutPanic(t *testing.T) {
+	SetErrorEncoder(func(ctx context.Context, err error) interface{} {
+		return err.Error()
+	})
+
+	logs := make(logWriter, 1)
+	log.SetOutput(logs)
+	defer log.SetOutput(os.Stderr)
+
+	// The panic after the timeout response is logged
+	late := Wrap(func() error {
+		time.Sleep(30 * time.Millisecond)
+		panic("late boom")
+	}).Name("late").Timeout(10 * time.Millisecond)
+	recorder := httptest.NewRecorder()
+	late.ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, "/", nil))
+	require.Equal(t, http.StatusServiceUnavailable, recorder.Code)
+
+	select {
+	case line := <-logs:
+		require.Contains(t, line, "fn: late panicked after the timeout: late boom")
+	case <-time.After(time.Second):
+		t.Fatal("the panic after the timeout is not logged")
+	}
+}
+
+func TestClientTimeout(t *testing.T) {
+	SetErrorEncoder(func(ctx context.Context, err error) interface{} {
+		return err.Error()
+	})
+	SetClientTimeoutHeader("X-Request-Timeout", 50*time.Millisecond)
+	defer SetClientTimeoutHeader("", 0)
+
+	deadlines := make(chan time.Duration, 1)
+	handler := func(ctx context.Context) error {
+		d, _ := ctx.Deadline()
+		deadlines <- time.Until(d)
+		<-ctx.Done()
+		return ctx.Err()
+	}
+
+	serve := func(h http.Handler, timeout string) int {
+		request := httptest.NewRequest(http.MethodGet, "/", nil)
+		request.Header.Set("X-Request-Timeout", timeout)
+		recorder := httptest.NewRecorder()
+		h.ServeHTTP(recorder, request)
+		return recorder.Code
+	}
+
+	require.Equal(t, http.StatusGatewayTimeout, serve(Wrap(handler), "10"))
+	require.True(t, <-deadlines <= 10*time.Millisecond)
+
+	// Capped by the max client timeout
+	require.Equal(t, http.StatusGatewayTimeout, serve(Wrap(handler), "1h"))
+	require.True(t, <-deadlines <= 50*time.Millisecond)
+
+	// Capped by the timeout of handler
+	require.Equal(t, http.StatusServiceUnavailable, serve(Wrap(handler).Timeout(5*time.Millisecond), "20ms"))
+	require.True(t, <-deadlines <= 5*time.Millisecond)
+	require.Equal(t, http.StatusGatewayTimeout, serve(Wrap(handler).Timeout(time.Second), "20ms"))
+	require.True(t, <-deadlines <= 20*time.Millisecond)
+
+	for value, expected := range map[string]time.Duration{
+		"":                   0,
+		"100":                100 * time.Millisecond,
+		"1.5s":               1500 * time.Millisecond,
+		"-1":                 0,
+		"-1s":                0,
+		"0":                  0,
+		"abc":                0,
+		"1e3":                0,
+		"250ms":              250 * time.Millisecond,
+		"999999999999999999": 0,
+	} {
+		request := httptest.NewRequest(http.MethodGet, "/", nil)
+		request.Header.Set("X-Request-Timeout", value)
+		require.Equal(t, expected, clientTimeout(request), value)
+	}
+}
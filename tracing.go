@@ -0,0 +1,300 @@
+// Copyright 2026 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package fn
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	// TraceParentHeader is the W3C trace context header carrying the trace
+	// id, the parent span id and the trace flags
+	TraceParentHeader = "traceparent"
+
+	// TraceStateHeader is the W3C trace context header carrying the vendor
+	// specific trace state
+	TraceStateHeader = "tracestate"
+
+	// TraceFlagSampled is the sampled flag of trace flags
+	TraceFlagSampled byte = 0x01
+)
+
+var errInvalidTraceParent = errors.New("invalid traceparent")
+
+type (
+	// TraceContext identifies a span in a trace, it is propagated through
+	// the W3C traceparent and tracestate headers
+	TraceContext struct {
+		TraceID [16]byte
+		SpanID  [8]byte
+		Flags   byte
+		State   string
+		// Remote reports whether the trace context is parsed from a request
+		Remote bool
+	}
+
+	// Tracer creates the spans of the requests served by the wrapped
+	// handlers, it is the bridge to a tracing SDK (e.g. OpenTelemetry).
+	Tracer interface {
+		// Start starts a span as a child of the parent, the parent is
+		// invalid (see TraceContext.IsValid) when starting a new trace
+		Start(ctx context.Context, name string, parent TraceContext) Span
+	}
+
+	// Span represents an operation of a trace
+	Span interface {
+		// TraceContext returns the trace context identifying the span
+		TraceContext() TraceContext
+		SetAttribute(key string, value interface{})
+		RecordError(err error)
+		End()
+	}
+
+	traceKey struct{}
+
+	// tracing starts a span for every request and a child span for every
+	// phase of the request
+	tracing struct {
+		tracer Tracer
+	}
+
+	tracingProbe struct {
+		ctx     context.Context
+		tracer  Tracer
+		span    Span
+		current Span
+		name    string
+	}
+)
+
+// SetTracer enables tracing all wrapped handlers, nil disables it.
+// The trace context of a request is parsed from the traceparent and
+// tracestate headers, and available in the context of plugins and the
+// wrapped function (see TraceContextFromContext).
+func SetTracer(t Tracer) {
+	if t == nil {
+		observers[tracingSlot] = nil
+		return
+	}
+	observers[tracingSlot] = &tracing{tracer: t}
+}
+
+func (t *tracing) observe(ctx context.Context, x *exchange) (context.Context, probe) {
+	parent, _ := ExtractTraceContext(x.request.Header)
+	span := t.tracer.Start(ctx, x.name, parent)
+	span.SetAttribute("http.method", x.request.Method)
+	span.SetAttribute("http.target", x.request.URL.RequestURI())
+	span.SetAttribute("http.route", x.name)
+	ctx = context.WithValue(ctx, traceKey{}, span.TraceContext())
+	return ctx, &tracingProbe{ctx: ctx, tracer: t.tracer, span: span, name: x.name}
+}
+
+func (p *tracingProbe) beginPhase(ph phase) {
+	p.current = p.tracer.Start(p.ctx, p.name+" "+ph.String(), p.span.TraceContext())
+	p.current.SetAttribute("fn.phase", ph.String())
+}
+
+func (p *tracingProbe) endPhase(ph phase, err error) {
+	if err != nil {
+		p.current.RecordError(err)
+	}
+	p.current.End()
+	p.current = nil
+}
+
+func (p *tracingProbe) end(x *exchange) {
+	p.span.SetAttribute("http.status_code", x.status())
+	if x.err != nil {
+		p.span.RecordError(x.err)
+	}
+	p.span.End()
+}
+
+// TraceContextFromContext returns the trace context of the request span
+func TraceContextFromContext(ctx context.Context) (TraceContext, bool) {
+	tc, ok := ctx.Value(traceKey{}).(TraceContext)
+	return tc, ok
+}
+
+// ExtractTraceContext parses the trace context from the traceparent and
+// tracestate headers
+func ExtractTraceContext(header http.Header) (TraceContext, error) {
+	tc, err := ParseTraceParent(header.Get(TraceParentHeader))
+	if err != nil {
+		return TraceContext{}, err
+	}
+	tc.State = strings.Join(header[http.CanonicalHeaderKey(TraceStateHeader)], ",")
+	tc.Remote = true
+	return tc, nil
+}
+
+// InjectTraceContext sets the traceparent and tracestate headers of an
+// outgoing request to propagate the trace context of ctx
+func InjectTraceContext(ctx context.Context, header http.Header) {
+	tc, ok := TraceContextFromContext(ctx)
+	if !ok || !tc.IsValid() {
+		return
+	}
+	header.Set(TraceParentHeader, tc.TraceParent())
+	if tc.State != "" {
+		header.Set(TraceStateHeader, tc.State)
+	} else {
+		header.Del(TraceStateHeader)
+	}
+}
+
+// ParseTraceParent parses the value of traceparent header, e.g.
+// 00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01
+func ParseTraceParent(s string) (TraceContext, error) {
+	var tc TraceContext
+	// The future versions may append fields separated by dash
+	if len(s) < 55 || (len(s) > 55 && s[55] != '-') {
+		return tc, errInvalidTraceParent
+	}
+	if s[2] != '-' || s[35] != '-' || s[52] != '-' {
+		return tc, errInvalidTraceParent
+	}
+
+	var version [1]byte
+	if !decodeLowerHex(version[:], s[0:2]) || version[0] == 0xff || (version[0] == 0 && len(s) != 55) {
+		return tc, errInvalidTraceParent
+	}
+	var flags [1]byte
+	if !decodeLowerHex(tc.TraceID[:], s[3:35]) ||
+		!decodeLowerHex(tc.SpanID[:], s[36:52]) ||
+		!decodeLowerHex(flags[:], s[53:55]) {
+		return tc, errInvalidTraceParent
+	}
+	tc.Flags = flags[0]
+	if !tc.IsValid() {
+		return TraceContext{}, errInvalidTraceParent
+	}
+	return tc, nil
+}
+
+func decodeLowerHex(dst []byte, s string) bool {
+	if s != strings.ToLower(s) {
+		return false
+	}
+	_, err := hex.Decode(dst, []byte(s))
+	return err == nil
+}
+
+// IsValid reports whether both the trace id and the span id are non-zero
+func (tc TraceContext) IsValid() bool {
+	return tc.TraceID != [16]byte{} && tc.SpanID != [8]byte{}
+}
+
+// IsSampled reports whether the sampled flag is set
+func (tc TraceContext) IsSampled() bool {
+	return tc.Flags&TraceFlagSampled != 0
+}
+
+// TraceParent formats the trace context as the value of traceparent header
+func (tc TraceContext) TraceParent() string {
+	return "00-" + hex.EncodeToString(tc.TraceID[:]) + "-" + hex.EncodeToString(tc.SpanID[:]) + "-" + hex.EncodeToString([]byte{tc.Flags})
+}
+
+type (
+	// MemoryTracer is a Tracer keeping the ended spans in memory, which is
+	// intended for tests
+	MemoryTracer struct {
+		mu    sync.Mutex
+		spans []*MemorySpan
+	}
+
+	// MemorySpan is a span recorded by MemoryTracer
+	MemorySpan struct {
+		tracer *MemoryTracer
+
+		Name       string
+		Context    TraceContext
+		Parent     TraceContext
+		Attributes map[string]interface{}
+		Errors     []error
+		StartTime  time.Time
+		EndTime    time.Time
+	}
+)
+
+// NewMemoryTracer returns a tracer recording the spans in memory
+func NewMemoryTracer() *MemoryTracer {
+	return &MemoryTracer{}
+}
+
+// Start implements the Tracer interface
+func (t *MemoryTracer) Start(ctx context.Context, name string, parent TraceContext) Span {
+	tc := TraceContext{Flags: TraceFlagSampled}
+	if parent.IsValid() {
+		tc.TraceID = parent.TraceID
+		tc.Flags = parent.Flags
+		tc.State = parent.State
+	} else {
+		_, _ = rand.Read(tc.TraceID[:])
+	}
+	_, _ = rand.Read(tc.SpanID[:])
+	return &MemorySpan{
+		tracer:     t,
+		Name:       name,
+		Context:    tc,
+		Parent:     parent,
+		Attributes: map[string]interface{}{},
+		StartTime:  time.Now(),
+	}
+}
+
+// Spans returns the ended spans in the order they are ended
+func (t *MemoryTracer) Spans() []*MemorySpan {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return append([]*MemorySpan(nil), t.spans...)
+}
+
+// Reset drops the recorded spans
+func (t *MemoryTracer) Reset() {
+	t.mu.Lock()
+	t.spans = nil
+	t.mu.Unlock()
+}
+
+// TraceContext implements the Span interface
+func (s *MemorySpan) TraceContext() TraceContext {
+	return s.Context
+}
+
+// SetAttribute implements the Span interface
+func (s *MemorySpan) SetAttribute(key string, value interface{}) {
+	s.Attributes[key] = value
+}
+
+// RecordError implements the Span interface
+func (s *MemorySpan) RecordError(err error) {
+	s.Errors = append(s.Errors, err)
+}
+
+// End implements the Span interface
+func (s *MemorySpan) End() {
+	s.EndTime = time.Now()
+	s.tracer.mu.Lock()
+	s.tracer.spans = append(s.tracer.spans, s)
+	s.tracer.mu.Unlock()
+}
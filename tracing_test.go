@@ -0,0 +1,126 @@
+// Copyright 2026 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package fn
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseTraceParent(t *testing.T) {
+	tc, err := ParseTraceParent("00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01")
+	require.NoError(t, err)
+	require.True(t, tc.IsValid())
+	require.True(t, tc.IsSampled())
+	require.Equal(t, "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01", tc.TraceParent())
+
+	_, err = ParseTraceParent("01-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-00-future")
+	require.NoError(t, err)
+
+	for _, s := range []string{
+		"",
+		"00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-0",
+		"00-4BF92F3577B34DA6A3CE929D0E0E4736-00f067aa0ba902b7-01",
+		"00-00000000000000000000000000000000-00f067aa0ba902b7-01",
+		"00-4bf92f3577b34da6a3ce929d0e0e4736-0000000000000000-01",
+		"ff-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01",
+		"00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01-extra",
+		"00_4bf92f3577b34da6a3ce929d0e0e4736_00f067aa0ba902b7_01",
+		"00-4bf92f3577b34da6a3ce929d0e0e473x-00f067aa0ba902b7-01",
+	} {
+		_, err := ParseTraceParent(s)
+		require.Error(t, err, s)
+	}
+}
+
+func TestTracer(t *testing.T) {
+	tracer := NewMemoryTracer()
+	SetTracer(tracer)
+	defer SetTracer(nil)
+
+	var (
+		handlerTrace TraceContext
+		outgoing     = http.Header{}
+	)
+	handler := Wrap(func(ctx context.Context, req *testRequest) (*testResponse, error) {
+		handlerTrace, _ = TraceContextFromContext(ctx)
+		InjectTraceContext(ctx, outgoing)
+		if req.Foo == "fail" {
+			return nil, ErrorWithStatusCode(errors.New("unavailable"), http.StatusServiceUnavailable)
+		}
+		return &testResponse{Message: req.Foo}, nil
+	}).Name("echo")
+
+	request := httptest.NewRequest(http.MethodPost, "/echo?x=1", strings.NewReader(`{"foo":"bar"}`))
+	request.Header.Set(TraceParentHeader, "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01")
+	request.Header.Set(TraceStateHeader, "congo=t61rcWkgMzE")
+	handler.ServeHTTP(httptest.NewRecorder(), request)
+
+	spans := tracer.Spans()
+	require.Len(t, spans, 5)
+	names := make([]string, len(spans))
+	for i, span := range spans {
+		names[i] = span.Name
+	}
+	require.Equal(t, []string{"echo plugin", "echo decode", "echo handler", "echo encode", "echo"}, names)
+
+	root := spans[4]
+	require.True(t, root.Parent.Remote)
+	require.Equal(t, "00f067aa0ba902b7", root.Parent.TraceParent()[36:52])
+	require.Equal(t, root.Parent.TraceID, root.Context.TraceID)
+	require.Equal(t, "congo=t61rcWkgMzE", root.Context.State)
+	require.Equal(t, "POST", root.Attributes["http.method"])
+	require.Equal(t, "/echo?x=1", root.Attributes["http.target"])
+	require.Equal(t, http.StatusOK, root.Attributes["http.status_code"])
+	require.Empty(t, root.Errors)
+	for _, span := range spans[:4] {
+		require.Equal(t, root.Context, span.Parent)
+	}
+
+	require.Equal(t, root.Context, handlerTrace)
+	require.Equal(t, root.Context.TraceParent(), outgoing.Get(TraceParentHeader))
+	require.Equal(t, "congo=t61rcWkgMzE", outgoing.Get(TraceStateHeader))
+
+	// New trace without a valid traceparent, the error is recorded by both
+	// the handler phase and the request span
+	tracer.Reset()
+	request = httptest.NewRequest(http.MethodPost, "/echo", strings.NewReader(`{"foo":"fail"}`))
+	request.Header.Set(TraceParentHeader, "invalid")
+	handler.ServeHTTP(httptest.NewRecorder(), request)
+
+	spans = tracer.Spans()
+	require.Len(t, spans, 5)
+	root = spans[4]
+	require.False(t, root.Parent.IsValid())
+	require.True(t, root.Context.IsValid())
+	require.Equal(t, http.StatusServiceUnavailable, root.Attributes["http.status_code"])
+	require.Len(t, root.Errors, 1)
+	require.Len(t, spans[2].Errors, 1)
+	require.Empty(t, spans[1].Errors)
+
+	// The trace context is unavailable if tracing is disabled
+	SetTracer(nil)
+	tracer.Reset()
+	handlerTrace = TraceContext{}
+	handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodPost, "/echo", strings.NewReader(`{}`)))
+	require.Empty(t, tracer.Spans())
+	require.False(t, handlerTrace.IsValid())
+}
@@ -25,7 +25,10 @@ import (
 
 type valuer func(r *http.Request) (reflect.Value, error)
 
-var contextType = reflect.TypeOf((*context.Context)(nil)).Elem()
+var (
+	contextType = reflect.TypeOf((*context.Context)(nil)).Elem()
+	errorType   = reflect.TypeOf((*error)(nil)).Elem()
+)
 
 // BenchmarkIsBuiltinType-8   	100000000	        23.1 ns/op	       0 B/op	       0 allocs/op
 var supportTypes = map[reflect.Type]valuer{
@@ -40,6 +43,12 @@ var supportTypes = map[reflect.Type]valuer{
 	reflect.TypeOf((*http.Request)(nil)):         requestValuer,     // raw request
 }
 
+// contextValuer extracts an argument from the context returned by plugins,
+// e.g. the claims of verified token
+type contextValuer func(ctx context.Context) (reflect.Value, error)
+
+var contextTypes = map[reflect.Type]contextValuer{}
+
 var maxMemory = int64(2 * 1024 * 1024)
 
 type uniform struct {
@@ -69,7 +78,7 @@ func headerValuer(r *http.Request) (reflect.Value, error) {
 func multipartValuer(r *http.Request) (reflect.Value, error) {
 	err := r.ParseMultipartForm(maxMemory)
 	if err != nil {
-		return reflect.Value{}, err
+		return reflect.Value{}, formError(err)
 	}
 	return reflect.ValueOf(r.MultipartForm), nil
 }
@@ -77,7 +86,7 @@ func multipartValuer(r *http.Request) (reflect.Value, error) {
 func formValuer(r *http.Request) (reflect.Value, error) {
 	err := r.ParseForm()
 	if err != nil {
-		return reflect.Value{}, nil
+		return reflect.Value{}, formError(err)
 	}
 	return reflect.ValueOf(Form{uniform{r.Form}}), nil
 }
@@ -85,7 +94,7 @@ func formValuer(r *http.Request) (reflect.Value, error) {
 func postFromValuer(r *http.Request) (reflect.Value, error) {
 	err := r.ParseForm()
 	if err != nil {
-		return reflect.Value{}, nil
+		return reflect.Value{}, formError(err)
 	}
 	return reflect.ValueOf(PostForm{uniform{r.PostForm}}), nil
 }
@@ -93,7 +102,7 @@ func postFromValuer(r *http.Request) (reflect.Value, error) {
 func formPtrValuer(r *http.Request) (reflect.Value, error) {
 	err := r.ParseForm()
 	if err != nil {
-		return reflect.Value{}, nil
+		return reflect.Value{}, formError(err)
 	}
 	return reflect.ValueOf(&Form{uniform{r.Form}}), nil
 }
@@ -101,11 +110,27 @@ func formPtrValuer(r *http.Request) (reflect.Value, error) {
 func postFromPtrValuer(r *http.Request) (reflect.Value, error) {
 	err := r.ParseForm()
 	if err != nil {
-		return reflect.Value{}, nil
+		return reflect.Value{}, formError(err)
 	}
 	return reflect.ValueOf(&PostForm{uniform{r.PostForm}}), nil
 }
 
+// formError classifies the error of parsing form: the oversize bodies are
+// reported with 413 and the malformed ones with 400, the errors which carry
+// status code already are returned as is.
+func formError(err error) error {
+	if _, ok := UnwrapErrorStatusCode(err); ok {
+		return err
+	}
+	for e := err; e != nil; e = Unwrap(e) {
+		if e == multipart.ErrMessageTooLarge || e.Error() == "http: POST too large" ||
+			e.Error() == "http: request body too large" {
+			return ErrorWithStatusCode(err, http.StatusRequestEntityTooLarge)
+		}
+	}
+	return ErrorWithStatusCode(err, http.StatusBadRequest)
+}
+
 func requestValuer(r *http.Request) (reflect.Value, error) {
 	return reflect.ValueOf(r), nil
 }
@@ -114,3 +139,8 @@ func isBuiltinType(t reflect.Type) bool {
 	_, ok := supportTypes[t]
 	return ok
 }
+
+func isContextValueType(t reflect.Type) bool {
+	_, ok := contextTypes[t]
+	return ok
+}
@@ -15,10 +15,86 @@
 package fn
 
 import (
+	"bytes"
+	"context"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
 	"reflect"
+	"strings"
 	"testing"
+
+	"github.com/stretchr/testify/require"
 )
 
+func TestFormParseError(t *testing.T) {
+	handlers := map[string]*fn{
+		"Form":      Wrap(withForm),
+		"PostForm":  Wrap(withPostForm),
+		"*Form":     Wrap(withFormPtr),
+		"*PostForm": Wrap(withPostFormPtr),
+		"FromForm": Wrap(func(ctx context.Context, opts *testListOptions) (*testResponse, error) {
+			return successResponse, nil
+		}),
+	}
+
+	cases := []struct {
+		name        string
+		contentType string
+		body        string
+		statusCode  int
+	}{
+		{"malformed", "application/x-www-form-urlencoded", "a=%zz", http.StatusBadRequest},
+		{"too large", "application/x-www-form-urlencoded", strings.Repeat("a", 10<<20+1), http.StatusRequestEntityTooLarge},
+	}
+
+	for name, handler := range handlers {
+		for _, c := range cases {
+			recorder := httptest.NewRecorder()
+			request, err := http.NewRequest(http.MethodPost, "/", strings.NewReader(c.body))
+			require.NoError(t, err)
+			request.Header.Set("Content-Type", c.contentType)
+			require.NotPanics(t, func() { handler.ServeHTTP(recorder, request) }, name)
+			require.Equal(t, c.statusCode, recorder.Code, "%s: %s", name, c.name)
+		}
+	}
+}
+
+func TestMultipartFormParseError(t *testing.T) {
+	handler := Wrap(withMultipartForm)
+
+	recorder := httptest.NewRecorder()
+	request, err := http.NewRequest(http.MethodPost, "/", strings.NewReader("--x\r\n"))
+	require.NoError(t, err)
+	request.Header.Set("Content-Type", "multipart/form-data")
+	handler.ServeHTTP(recorder, request)
+	require.Equal(t, http.StatusBadRequest, recorder.Code)
+
+	recorder = httptest.NewRecorder()
+	request, err = http.NewRequest(http.MethodPost, "/", strings.NewReader("a=%zz"))
+	require.NoError(t, err)
+	request.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	handler.ServeHTTP(recorder, request)
+	require.Equal(t, http.StatusBadRequest, recorder.Code)
+
+	for value, statusCode := range map[string]int{
+		"value": http.StatusOK,
+		strings.Repeat("a", int(maxMemory)+10<<20+1): http.StatusRequestEntityTooLarge,
+	} {
+		body := &bytes.Buffer{}
+		writer := multipart.NewWriter(body)
+		require.NoError(t, writer.WriteField("key", value))
+		require.NoError(t, writer.Close())
+
+		recorder = httptest.NewRecorder()
+		request, err = http.NewRequest(http.MethodPost, "/", body)
+		require.NoError(t, err)
+		request.Header.Set("Content-Type", writer.FormDataContentType())
+		handler.ServeHTTP(recorder, request)
+		require.Equal(t, statusCode, recorder.Code)
+	}
+}
+
 func BenchmarkIsBuiltinType(b *testing.B) {
 	b.ReportAllocs()
 	b.ResetTimer()
@@ -17,8 +17,11 @@ package fn
 import (
 	"context"
 	"encoding/json"
+	"log"
 	"net/http"
 	"reflect"
+	"runtime/debug"
+	"time"
 )
 
 type (
@@ -30,8 +33,20 @@ type (
 
 	// fn represents a handler that contains a bundle of hooks
 	fn struct {
-		plugins []PluginFunc
-		adapter adapter
+		name          string
+		plugins       []PluginFunc
+		adapter       adapter
+		bodyLimit     int64
+		decodeOptions *DecodeOption
+		timeout       time.Duration
+		concurrency   *ConcurrencyLimiter
+		requirements  []string
+		cors          *cors
+		compression   *CompressionOptions
+		etag          *ETagOptions
+		cache         *CacheOptions
+		idempotency   *IdempotencyOptions
+		collapse      *CollapseOptions
 	}
 )
 
@@ -49,44 +64,247 @@ func failure(ctx context.Context, w http.ResponseWriter, err error) {
 	_ = json.NewEncoder(w).Encode(errorEncoder(ctx, err))
 }
 
-func success(ctx context.Context, w http.ResponseWriter, data interface{}) {
-	if data == nil || (reflect.ValueOf(data).Kind() == reflect.Ptr && reflect.ValueOf(data).IsNil()) {
-		w.WriteHeader(http.StatusNoContent)
-	} else {
-		_ = json.NewEncoder(w).Encode(responseEncoder(ctx, data))
+// success writes the payload with the status code returned by handler (200
+// if absent), the empty payloads (nil, nil pointers, slices, maps and
+// interfaces) are responded with 204 unless the status code is specified
+func success(ctx context.Context, w http.ResponseWriter, statusCode int, data interface{}) {
+	if isEmptyPayload(data) {
+		if statusCode == 0 {
+			statusCode = http.StatusNoContent
+		}
+		w.WriteHeader(statusCode)
+		return
+	}
+	if statusCode != 0 {
+		w.WriteHeader(statusCode)
+	}
+	_ = json.NewEncoder(w).Encode(responseEncoder(ctx, data))
+}
+
+func isEmptyPayload(data interface{}) bool {
+	if data == nil {
+		return true
 	}
+	switch v := reflect.ValueOf(data); v.Kind() {
+	case reflect.Ptr, reflect.Slice, reflect.Map:
+		return v.IsNil()
+	}
+	return false
 }
 
 func (fn *fn) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	ctx, x := newExchange(r.Context(), fn.name, w, r)
+	defer x.end()
+	w = x.writer
+	if handleCORS(fn.cors, w, r) {
+		return
+	}
+
 	w.Header().Set("Content-Type", "application/json; charset=utf-8")
+	limit := effectiveLimit(fn.bodyLimit, bodyLimit)
+	if err := limitBody(w, r, limit); err != nil {
+		x.failure(ctx, w, err)
+		return
+	}
+
+	if c := fn.compressionOptions(); c != nil {
+		if err := decompressRequest(r, c, limit); err != nil {
+			x.failure(ctx, w, err)
+			return
+		}
+		w = newCompressWriter(w, r, c)
+		if cw, ok := w.(*compressWriter); ok {
+			defer cw.close()
+		}
+	}
+
+	d, status := effectiveTimeout(fn.timeout, r)
+	if d <= 0 {
+		fn.serve(ctx, x, w, r)
+		return
+	}
+
+	// The handler runs in another goroutine with a buffered response, so
+	// that the timeout response can be sent even if the handler ignores the
+	// cancellation, and nothing is written after it.
+	ctx, cancel := context.WithTimeout(ctx, d)
+	defer cancel()
+	tw := newTimeoutWriter(w)
+	done := make(chan struct{})
+	panicked := make(chan interface{}, 1)
+	var stack []byte
+	go func() {
+		defer func() {
+			if p := recover(); p != nil {
+				stack = debug.Stack()
+				panicked <- p
+			}
+		}()
+		fn.serve(ctx, x, tw, r)
+		close(done)
+	}()
+
+	select {
+	case <-done:
+		tw.flush()
+	case p := <-panicked:
+		panic(p)
+	case <-ctx.Done():
+		// Nothing is written if the client has gone
+		tw.timeout()
+		if err := ctx.Err(); err == context.DeadlineExceeded {
+			x.abort(ctx, w, ErrorWithStatusCode(err, status))
+		} else {
+			x.abort(ctx, nil, err)
+		}
+		// The handler may still panic after the response, which can't be
+		// re-raised in the goroutine of request any more, log it instead
+		go func() {
+			select {
+			case <-done:
+			case p := <-panicked:
+				log.Printf("fn: %s panicked after the timeout: %v\n%s", x.name, p, stack)
+			}
+		}()
+	}
+}
+
+// serve runs the phases of serving a request
+func (fn *fn) serve(ctx context.Context, x *exchange, w http.ResponseWriter, r *http.Request) {
+	var err error
+	ctx = context.WithValue(ctx, responseHeaderKey{}, w.Header())
+	if fn.concurrency != nil {
+		if err := fn.concurrency.acquire(ctx, w.Header()); err != nil {
+			x.failure(ctx, w, err)
+			return
+		}
+		start := time.Now()
+		defer func() { fn.concurrency.release(time.Since(start)) }()
+	}
+
+	x.beginPhase(phasePlugin)
+	ctx, err = fn.runPlugins(ctx, r)
+	x.endPhase(phasePlugin, err)
+	if err != nil {
+		x.failure(ctx, w, err)
+		return
+	}
+
+	// The cache is looked up after the plugins, so that the requests are
+	// authenticated and authorized
+	if fn.cache != nil {
+		var (
+			hit *CachedResponse
+			rec *cacheRecorder
+		)
+		ctx, hit, rec = lookupCache(ctx, fn.cache, w, r)
+		if hit != nil {
+			x.beginPhase(phaseEncode)
+			err = writeCached(w, r, hit)
+			x.endPhase(phaseEncode, nil)
+			if err != nil {
+				x.failure(ctx, w, err)
+			}
+			return
+		}
+		if rec != nil {
+			w = rec
+			defer rec.store(ctx, r, fn.cache, time.Now())
+		}
+	}
+
+	if fn.idempotency != nil {
+		var (
+			replay *CachedResponse
+			ir     *idempotentRequest
+		)
+		replay, ir, err = beginIdempotent(ctx, fn.idempotency, w, r)
+		if err != nil {
+			x.failure(ctx, w, err)
+			return
+		}
+		if replay != nil {
+			x.beginPhase(phaseEncode)
+			writeReplay(w, replay)
+			x.endPhase(phaseEncode, nil)
+			return
+		}
+		if ir != nil {
+			w = ir.rec
+			defer ir.finish()
+		}
+	}
+
+	if fn.collapse != nil && r.Method == http.MethodGet {
+		if key := fn.collapse.key(ctx, r); key != "" {
+			fn.collapsed(ctx, x, w, r, key)
+			return
+		}
+	}
+	fn.execute(ctx, x, w, r)
+}
+
+// execute runs the decode, handler and encode phases
+func (fn *fn) execute(ctx context.Context, x *exchange, w http.ResponseWriter, r *http.Request) {
 	var (
-		ctx  = r.Context()
 		err  error
+		args []reflect.Value
 		resp interface{}
+		code int
+		v    *validated
 	)
 
+	x.beginPhase(phaseDecode)
+	args, err = fn.adapter.args(ctx, r, effectiveDecodeOption(fn.decodeOptions))
+	x.endPhase(phaseDecode, err)
+	if err != nil {
+		x.failure(ctx, w, err)
+		return
+	}
+
+	x.beginPhase(phaseHandler)
+	resp, code, err = fn.adapter.call(args)
+	x.endPhase(phaseHandler, err)
+	if err == nil {
+		v, err = validate(ctx, r, fn.etagOptions(), code, resp)
+	}
+	if err != nil {
+		x.failure(ctx, w, err)
+		return
+	}
+
+	x.beginPhase(phaseEncode)
+	if v != nil {
+		v.write(ctx, w, code, resp)
+	} else {
+		success(ctx, w, code, resp)
+	}
+	x.endPhase(phaseEncode, nil)
+}
+
+// runPlugins runs the global plugins and then the plugins of handler, and
+// evaluates the requirements at last
+func (fn *fn) runPlugins(ctx context.Context, r *http.Request) (context.Context, error) {
+	var err error
 	for _, b := range globalPlugins {
 		ctx, err = b(ctx, r)
 		if err != nil {
-			failure(ctx, w, err)
-			return
+			return ctx, err
 		}
 	}
 
 	for _, b := range fn.plugins {
 		ctx, err = b(ctx, r)
 		if err != nil {
-			failure(ctx, w, err)
-			return
+			return ctx, err
 		}
 	}
 
-	resp, err = fn.adapter.invoke(ctx, w, r)
-	if err != nil {
-		failure(ctx, w, err)
-		return
+	// The requirements are evaluated after the authentication plugins
+	if err := fn.authorize(ctx, r); err != nil {
+		return ctx, err
 	}
-	success(ctx, w, resp)
+	return ctx, nil
 }
 
 func (fn *fn) Plugin(before ...PluginFunc) *fn {
@@ -98,6 +316,105 @@ func (fn *fn) Plugin(before ...PluginFunc) *fn {
 	return fn
 }
 
+// Name names the handler, which is used as the label of metrics and the
+// name of spans, the name of wrapped function by default.
+func (fn *fn) Name(name string) *fn {
+	fn.name = name
+	return fn
+}
+
+// BodyLimit limits the size of request body in bytes, which overrides the
+// limit of group and the global one. Negative means unlimited.
+func (fn *fn) BodyLimit(n int64) *fn {
+	fn.bodyLimit = n
+	return fn
+}
+
+// DecodeOptions sets the options of decoding JSON request body, which
+// overrides the options of group and the global ones.
+func (fn *fn) DecodeOptions(opts ...DecodeOption) *fn {
+	fn.decodeOptions = makeDecodeOption(opts)
+	return fn
+}
+
+// Timeout sets the timeout of the handler, which overrides the timeout of
+// group and the global one. Negative means no timeout.
+func (fn *fn) Timeout(d time.Duration) *fn {
+	fn.timeout = d
+	return fn
+}
+
+// Concurrency limits the number of requests served concurrently by the
+// limiter, which overrides the limiter of group
+func (fn *fn) Concurrency(l *ConcurrencyLimiter) *fn {
+	fn.concurrency = l
+	return fn
+}
+
+// Require adds the requirements (e.g. permissions or scopes) of the handler,
+// which are evaluated by the Authorizer after the plugins.
+//
+//	http.Handle("/orders", fn.Wrap(createOrder).Require("orders:write"))
+func (fn *fn) Require(requirements ...string) *fn {
+	fn.requirements = append(fn.requirements, requirements...)
+	return fn
+}
+
+// Requirements returns the requirements of the handler, including the ones
+// of group, e.g. for generating documents
+func (fn *fn) Requirements() []string {
+	return append([]string(nil), fn.requirements...)
+}
+
+// Compression sets the options of compression, which overrides the options
+// of group and the global ones
+func (fn *fn) Compression(opts *CompressionOptions) *fn {
+	fn.compression = opts
+	return fn
+}
+
+func (fn *fn) compressionOptions() *CompressionOptions {
+	if fn.compression != nil {
+		return fn.compression
+	}
+	return globalCompression
+}
+
+// ETag enables generating ETags for the handler, which overrides the options
+// of group and the global ones
+func (fn *fn) ETag(opts *ETagOptions) *fn {
+	fn.etag = opts
+	return fn
+}
+
+// Cache enables caching the successful responses of GET, which overrides the
+// options of group
+func (fn *fn) Cache(opts *CacheOptions) *fn {
+	fn.cache = opts
+	return fn
+}
+
+// Idempotency enables deduplicating the unsafe requests by the
+// Idempotency-Key header, which overrides the options of group
+func (fn *fn) Idempotency(opts *IdempotencyOptions) *fn {
+	fn.idempotency = opts
+	return fn
+}
+
+// Collapse enables sharing a single invocation among the concurrent
+// identical GET requests, which overrides the options of group
+func (fn *fn) Collapse(opts *CollapseOptions) *fn {
+	fn.collapse = opts
+	return fn
+}
+
+func (fn *fn) etagOptions() *ETagOptions {
+	if fn.etag != nil {
+		return fn.etag
+	}
+	return globalETag
+}
+
 func init() {
 	errorEncoder = func(ctx context.Context, err error) interface{} {
 		return err.Error()
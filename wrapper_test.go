@@ -82,6 +82,16 @@ func withAll(io.ReadCloser, *testRequest, Form, PostForm, http.Header, *multipar
 	return nil, nil
 }
 
+type testID int64
+
+func withReqValue(testRequest) (*testResponse, error)       { return successResponse, nil }
+func withSlice([]*testRequest) (*testResponse, error)       { return successResponse, nil }
+func withMap(map[string]testRequest) (*testResponse, error) { return successResponse, nil }
+func withScalar(testID) (*testResponse, error)              { return successResponse, nil }
+func withSliceAndHeader(http.Header, []testRequest) (*testResponse, error) {
+	return successResponse, nil
+}
+
 func TestHandler(t *testing.T) {
 	Wrap(withNone)
 	Wrap(withBody)
@@ -98,6 +108,62 @@ func TestHandler(t *testing.T) {
 	Wrap(withAll)
 	Wrap(withInContext)
 	Wrap(withInContextAndPayload)
+	Wrap(withReqValue)
+	Wrap(withSlice)
+	Wrap(withMap)
+	Wrap(withScalar)
+	Wrap(withSliceAndHeader)
+
+	require.Panics(t, func() { Wrap(func(interface{}) (*testResponse, error) { return nil, nil }) })
+	require.Panics(t, func() { Wrap(func(func()) (*testResponse, error) { return nil, nil }) })
+	require.Panics(t, func() { Wrap(func(http.Header, chan int) (*testResponse, error) { return nil, nil }) })
+	require.Panics(t, func() { Wrap(func([]testRequest, *testRequest) (*testResponse, error) { return nil, nil }) })
+}
+
+func TestNonPointerRequest(t *testing.T) {
+	cases := []struct {
+		handler interface{}
+		body    string
+		expect  string
+	}{
+		{
+			handler: func(req testRequest) (*testResponse, error) {
+				return &testResponse{Code: req.Bar, Message: req.Foo}, nil
+			},
+			body:   `{"foo":"hello","bar":1}`,
+			expect: `{"code":1,"message":"hello"}`,
+		},
+		{
+			handler: func(ctx context.Context, items []testRequest) (*testResponse, error) {
+				return &testResponse{Code: len(items), Message: items[1].Foo}, nil
+			},
+			body:   `[{"foo":"a"},{"foo":"b"}]`,
+			expect: `{"code":2,"message":"b"}`,
+		},
+		{
+			handler: func(settings map[string]int) (*testResponse, error) {
+				return &testResponse{Code: settings["a"] + settings["b"]}, nil
+			},
+			body:   `{"a":1,"b":2}`,
+			expect: `{"code":3,"message":""}`,
+		},
+		{
+			handler: func(header http.Header, id testID) (*testResponse, error) {
+				return &testResponse{Code: int(id)}, nil
+			},
+			body:   `42`,
+			expect: `{"code":42,"message":""}`,
+		},
+	}
+
+	for _, c := range cases {
+		recorder := httptest.NewRecorder()
+		request, err := http.NewRequest(http.MethodPost, "", bytes.NewBufferString(c.body))
+		require.NoError(t, err)
+		Wrap(c.handler).ServeHTTP(recorder, request)
+		require.Equal(t, http.StatusOK, recorder.Code)
+		require.JSONEq(t, c.expect, recorder.Body.String())
+	}
 }
 
 func TestPlugin(t *testing.T) {
@@ -274,3 +340,49 @@ func BenchmarkGenericAdapter_Invoke(b *testing.B) {
 		handler.ServeHTTP(recorder, request)
 	}
 }
+
+func TestReturnShapes(t *testing.T) {
+	cases := []struct {
+		handler    interface{}
+		statusCode int
+		body       string
+	}{
+		{func() error { return nil }, http.StatusNoContent, ``},
+		{func(ctx context.Context) error { return errors.New("failed") }, http.StatusBadRequest, `"failed"`},
+		{func() (*testResponse, int, error) { return successResponse, http.StatusCreated, nil }, http.StatusCreated, `{"code":0,"message":"success"}`},
+		{func() (*testResponse, int, error) { return nil, http.StatusAccepted, nil }, http.StatusAccepted, ``},
+		{func() (*testResponse, int, error) { return nil, 0, nil }, http.StatusNoContent, ``},
+		{func() (*testResponse, int, error) {
+			return successResponse, http.StatusCreated, ErrorWithStatusCode(errors.New("conflict"), http.StatusConflict)
+		}, http.StatusConflict, `"conflict"`},
+		{func() (testResponse, error) { return *successResponse, nil }, http.StatusOK, `{"code":0,"message":"success"}`},
+		{func() ([]testResponse, error) { return nil, nil }, http.StatusNoContent, ``},
+		{func() ([]testResponse, error) { return []testResponse{}, nil }, http.StatusOK, `[]`},
+		{func() (map[string]int, error) { return nil, nil }, http.StatusNoContent, ``},
+		{func() (io.Reader, error) { return nil, nil }, http.StatusNoContent, ``},
+	}
+
+	SetErrorEncoder(func(ctx context.Context, err error) interface{} {
+		return err.Error()
+	})
+	SetResponseEncoder(func(ctx context.Context, payload interface{}) interface{} {
+		return payload
+	})
+	for i, c := range cases {
+		recorder := httptest.NewRecorder()
+		request, err := http.NewRequest(http.MethodGet, "", nil)
+		require.NoError(t, err)
+		Wrap(c.handler).ServeHTTP(recorder, request)
+		require.Equal(t, c.statusCode, recorder.Code, "case %d", i)
+		if c.body == "" {
+			require.Empty(t, recorder.Body.String(), "case %d", i)
+		} else {
+			require.JSONEq(t, c.body, recorder.Body.String(), "case %d", i)
+		}
+	}
+
+	require.Panics(t, func() { Wrap(func() {}) })
+	require.Panics(t, func() { Wrap(func() *testResponse { return nil }) })
+	require.Panics(t, func() { Wrap(func() (*testResponse, string, error) { return nil, "", nil }) })
+	require.Panics(t, func() { Wrap(func() (*testResponse, *testResponse) { return nil, nil }) })
+}